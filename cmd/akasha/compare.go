@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	
+	"github.com/fatih/color"
+	"github.com/yantianyv/AkashaTerminal/internal/config"
+	"github.com/yantianyv/AkashaTerminal/internal/operations"
+	"github.com/yantianyv/AkashaTerminal/internal/providers"
+	"github.com/yantianyv/AkashaTerminal/internal/utils"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// compareResult 是一个配置对同一段对话的回答
+type compareResult struct {
+	Profile    string
+	Model      string
+	Response   types.Response
+	Operations []types.FileOperation
+	ParseErr   error // 回复不是有效的操作指令
+	Err        error // 请求失败
+	Latency    time.Duration
+	Cost       string // 按价格表计算的费用，未配置价格时为空
+}
+
+// compareProfiles 并发地将同一段对话发送给多个配置，结果顺序与 names 一致。
+// 各配置单独创建供应商，不使用其回退链；支持结构化输出的配置按 JSON 格式回复
+func compareProfiles(ctx context.Context, cfgMgr *config.ConfigManager, names []string, messages []types.Message) []compareResult {
+	results := make([]compareResult, len(names))
+	
+	var wg sync.WaitGroup
+	for i, name := range names {
+		results[i].Profile = name
+	
+		profile, err := cfgMgr.GetProfile(name)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		provider, err := providers.CreateProvider(profile)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Model = provider.GetModel()
+		reqMessages, structured := requestReplyFormat(provider, messages)
+	
+		wg.Add(1)
+		go func(r *compareResult) {
+			defer wg.Done()
+	
+			start := time.Now()
+			if structured {
+				r.Response, r.Err = providers.StreamStructured(ctx, provider, reqMessages, operations.ReplyFormat(), nil)
+			} else {
+				r.Response, r.Err = provider.SendRequest(ctx, reqMessages)
+			}
+			r.Latency = time.Since(start)
+			if r.Err != nil {
+				return
+			}
+			if r.Response.Model != "" {
+				r.Model = r.Response.Model
+			}
+			_, r.Operations, r.ParseErr = parseOperations(r.Response.Content, structured)
+		}(&results[i])
+	}
+	wg.Wait()
+	
+	return results
+}
+
+// showComparison 先以表格列出各配置的耗时与用量，再逐个列出解析出的操作
+func showComparison(results []compareResult) {
+	// 中文表头每个字占两列，宽度相应减少
+	fmt.Printf("\n%-4s %-14s %-26s %7s %7s %7s %8s  %s\n", "#", "配置", "模型", "耗时", "输入", "输出", "费用", "操作")
+	for i, r := range results {
+		summary := fmt.Sprintf("%d 项", len(r.Operations))
+		switch {
+		case r.Err != nil:
+			summary = color.RedString("请求失败")
+		case r.ParseErr != nil:
+			summary = color.YellowString("无法解析")
+		}
+		cost := r.Cost
+		if cost == "" {
+			cost = "-"
+		}
+		fmt.Printf("%-4d %-16s %-28s %8.1fs %9d %9d %10s  %s\n", i+1, r.Profile, r.Model,
+			r.Latency.Seconds(), r.Response.Usage.PromptTokens, r.Response.Usage.CompletionTokens, cost, summary)
+	}
+	
+	for i, r := range results {
+		color.Cyan("\n[%d] %s", i+1, r.Profile)
+		switch {
+		case r.Err != nil:
+			color.Red("  %v", r.Err)
+		case r.ParseErr != nil:
+			color.Yellow("  %v", r.ParseErr)
+			fmt.Println(indent(r.Response.Content, "  "))
+		case len(r.Operations) == 0:
+			fmt.Println("  (没有操作)")
+		default:
+			for _, op := range r.Operations {
+				line := fmt.Sprintf("  %-6s %s", op.Action, op.Path)
+				if op.Mode != "" {
+					line += " (" + op.Mode + ")"
+				}
+				if op.Content != "" {
+					line += fmt.Sprintf(" · %d 行", strings.Count(op.Content, "\n")+1)
+				}
+				fmt.Println(line)
+			}
+		}
+	}
+}
+
+// chooseComparison 让用户选择要应用的一组操作，跳过或选择无效时返回 nil
+func chooseComparison(results []compareResult) *compareResult {
+	input := utils.UserPrompt(fmt.Sprintf("\n选择要应用的结果 (1-%d，回车跳过) > ", len(results)))
+	if input == "" {
+		return nil
+	}
+	
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 1 || n > len(results) {
+		utils.ShowWarning("无效的选择: " + input)
+		return nil
+	}
+	chosen := &results[n-1]
+	if chosen.Err != nil || chosen.ParseErr != nil {
+		utils.ShowWarning(fmt.Sprintf("%s 没有可应用的操作", chosen.Profile))
+		return nil
+	}
+	return chosen
+}
+
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	
+	"github.com/fatih/color"
+	"github.com/yantianyv/AkashaTerminal/internal/utils"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// maxImageSize 是单张图片的大小上限，多数视觉接口限制在 20MB 以内
+const maxImageSize = 20 << 20
+
+var (
+	// imageMediaTypes 是视觉接口普遍接受的图片格式
+	imageMediaTypes = map[string]bool{"image/png": true, "image/jpeg": true, "image/gif": true, "image/webp": true}
+	
+	// imageExtensions 用于识别输入中以 @ 引用的图片
+	imageExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true}
+)
+
+// imageAttachment 是等待随下一条消息发送的图片
+type imageAttachment struct {
+	Path  string
+	Image types.Image
+}
+
+// loadImage 读取本地图片，按文件内容而不是扩展名识别格式
+func loadImage(path string) (imageAttachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return imageAttachment{}, err
+	}
+	if info.Size() > maxImageSize {
+		return imageAttachment{}, fmt.Errorf("图片 %s 超过 %dMB", path, maxImageSize>>20)
+	}
+	
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return imageAttachment{}, err
+	}
+	mediaType := http.DetectContentType(data)
+	if !imageMediaTypes[mediaType] {
+		return imageAttachment{}, fmt.Errorf("%s 不是支持的图片格式 (PNG/JPEG/GIF/WebP)", path)
+	}
+	
+	return imageAttachment{Path: path, Image: types.Image{MediaType: mediaType, Data: data}}, nil
+}
+
+// handleImageCommand 处理 /image 命令：无参数时列出待发送的图片，
+// "/image <路径>" 附加一张图片，"/image clear" 清空
+func handleImageCommand(pending []imageAttachment, arg string) []imageAttachment {
+	switch arg {
+	case "":
+		if len(pending) == 0 {
+			fmt.Println("没有待发送的图片，使用 /image <路径> 附加，或在消息中以 @路径 引用")
+			return pending
+		}
+		fmt.Println("待发送的图片（随下一条消息发送）:")
+		for _, a := range pending {
+			fmt.Printf("  %s (%s, %.1fKB)\n", a.Path, a.Image.MediaType, float64(len(a.Image.Data))/1024)
+		}
+		return pending
+	case "clear":
+		utils.ShowSuccess("已清空待发送的图片")
+		return nil
+	}
+	
+	attachment, err := loadImage(arg)
+	if err != nil {
+		utils.ShowError("附加图片失败", err)
+		return pending
+	}
+	utils.ShowSuccess(fmt.Sprintf("已附加图片 %s，将随下一条消息发送", arg))
+	return append(pending, attachment)
+}
+
+// attachImages 将图片附加到对话的最后一条（当前用户）消息
+func attachImages(messages []types.Message, images []imageAttachment) {
+	if len(images) == 0 {
+		return
+	}
+	last := &messages[len(messages)-1]
+	for _, a := range images {
+		last.Images = append(last.Images, a.Image)
+	}
+	color.HiBlack("附带 %d 张图片", len(images))
+}
+
+// imageReferences 加载输入中以 @ 引用的本地图片，如 "按钮错位了 @screenshots/bug.png"。
+// 只处理图片扩展名的路径，文件不存在时视为普通文本
+func imageReferences(input string) []imageAttachment {
+	var attachments []imageAttachment
+	for _, word := range strings.Fields(input) {
+		if !strings.HasPrefix(word, "@") {
+			continue
+		}
+		path := strings.TrimRight(word[1:], ",.;:!?，。；：！？")
+		if !imageExtensions[strings.ToLower(filepath.Ext(path))] {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+	
+		attachment, err := loadImage(path)
+		if err != nil {
+			utils.ShowWarning(err.Error())
+			continue
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments
+}
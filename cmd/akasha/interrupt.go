@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// interruptHandler 将 Ctrl-C 转为取消当前请求，而不是结束整个会话
+type interruptHandler struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func newInterruptHandler() *interruptHandler {
+	h := &interruptHandler{}
+	
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		for range sigCh {
+			if !h.interrupt() {
+				fmt.Print("\n(输入 '/exit' 退出)\n> ")
+			}
+		}
+	}()
+	
+	return h
+}
+
+// begin 为一次请求创建可被 Ctrl-C 取消的上下文，请求结束后须调用返回的 done
+func (h *interruptHandler) begin(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	
+	h.mu.Lock()
+	h.cancel = cancel
+	h.mu.Unlock()
+	
+	return ctx, func() {
+		h.mu.Lock()
+		h.cancel = nil
+		h.mu.Unlock()
+		cancel()
+	}
+}
+
+// interrupt 取消进行中的请求，没有请求时返回 false
+func (h *interruptHandler) interrupt() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	
+	if h.cancel == nil {
+		return false
+	}
+	h.cancel()
+	h.cancel = nil
+	return true
+}
@@ -3,11 +3,15 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 	
+	"github.com/fatih/color"
 	"github.com/yantianyv/AkashaTerminal/internal/config"
 	"github.com/yantianyv/AkashaTerminal/internal/operations"
 	"github.com/yantianyv/AkashaTerminal/internal/providers"
@@ -20,8 +24,17 @@ var (
 	profileFlag = flag.String("profile", "", "使用指定的API配置")
 	maxDepth    = flag.Int("depth", 3, "目录扫描最大深度")
 	maxTokens   = flag.Int("tokens", 8192, "最大上下文Token数")
+	useIndex    = flag.Bool("index", false, "为项目文件建立向量索引，每轮按问题检索相关代码片段（供应商需支持 embeddings）")
+	noCache     = flag.Bool("no-cache", false, "本次运行不使用响应缓存（缓存在 profiles.json 的 cache 中开启）")
+	budget      = flag.String("budget", "", "本次会话的花费上限，如 5USD 或 10CNY；不写币种时使用当前模型价格的币种")
+	
+	cassettePath = flag.String("cassette", "", "录制/回放供应商 HTTP 请求的磁带文件（也可用 AKASHA_CASSETTE 指定）")
+	cassetteMode = flag.String("cassette-mode", providers.CassetteReplay, "磁带模式: record 或 replay")
 )
 
+// maxSnippets 是每轮从向量索引中检索的代码片段数
+const maxSnippets = 5
+
 func main() {
 	flag.Parse()
 	
@@ -49,13 +62,35 @@ func main() {
 		os.Exit(1)
 	}
 	
-	// 创建供应商实例
-	provider, err := providers.CreateProvider(apiConfig)
+	// 录制/回放模式需在创建供应商之前设置
+	if *cassettePath != "" {
+		if err := providers.UseCassette(*cassettePath, *cassetteMode); err != nil {
+			utils.ShowError("磁带配置无效", err)
+			os.Exit(1)
+		}
+	}
+	
+	// 响应缓存同样需在创建供应商之前设置
+	if cfgMgr.Cache != nil && !*noCache {
+		if err := providers.UseResponseCache(*cfgMgr.Cache); err != nil {
+			utils.ShowWarning("响应缓存不可用: " + err.Error())
+		}
+	}
+	
+	// 创建供应商实例（配置了 fallbacks 时组成回退链）
+	provider, err := createProvider(cfgMgr, apiConfig)
 	if err != nil {
 		utils.ShowError("创建AI提供程序失败", err)
 		os.Exit(1)
 	}
 	
+	// 上下文上限不能超过模型实际的上下文窗口
+	if info, ok := modelInfo(context.Background(), provider); ok && *maxTokens > info.ContextWindow {
+		utils.ShowWarning(fmt.Sprintf("-tokens %d 超过模型 %s 的上下文窗口 %d，已调整为 %d",
+			*maxTokens, info.ID, info.ContextWindow, info.ContextWindow))
+		*maxTokens = info.ContextWindow
+	}
+	
 	// 初始化状态管理
 	stateMgr := state.NewProjectState(*maxDepth, *maxTokens)
 	if err := stateMgr.ScanInitialDirectory("."); err != nil {
@@ -63,26 +98,110 @@ func main() {
 		os.Exit(1)
 	}
 	
+	// 向量索引在每轮提问前增量更新
+	var index *state.VectorIndex
+	if *useIndex {
+		index, err = openIndex(provider, stateMgr)
+		if err != nil {
+			utils.ShowWarning("向量索引不可用: " + err.Error())
+		}
+	}
+	
 	// 初始化文件管理器
 	fileMgr := operations.FileManager{}
 	
 	// 初始化Token管理
 	tokenMgr := state.NewTokenManager(*maxTokens)
 	
+	// 初始化费用统计，历史花费保存在配置目录
+	costs, err := state.NewCostTracker(cfgMgr.Prices, filepath.Join(filepath.Dir(cfgMgr.Path), "spending.json"))
+	if err != nil {
+		utils.ShowWarning("读取累计花费失败: " + err.Error())
+	}
+	if *budget != "" {
+		limit, err := state.ParseBudget(*budget)
+		if err == nil {
+			err = costs.SetBudget(limit, provider.GetModel())
+		}
+		if err != nil {
+			utils.ShowError("花费上限无效", err)
+			os.Exit(1)
+		}
+	}
+	
 	fmt.Printf("\n✨ github.com/yantianyv/AkashaTerminal v1.0 - 智能代码助手\n")
 	fmt.Printf("供应商: %s (%s)\n", provider.GetName(), provider.GetModel())
+	if len(apiConfig.Fallbacks) > 0 {
+		fmt.Printf("回退链: %s\n", strings.Join(apiConfig.Fallbacks, " → "))
+	}
 	fmt.Println("输入 '/exit' 退出, '/help' 查看帮助")
 	fmt.Println(strings.Repeat("=", 50))
 	
 	// 主交互循环
 	ctx := context.Background()
 	reader := bufio.NewReader(os.Stdin)
+	interrupts := newInterruptHandler()
+	lastReasoning := ""
+	sessionParams := types.GenerationParams{} // /set 设置的会话级生成参数
+	var pendingImages []imageAttachment      // /image 附加、随下一条消息发送的图片
 	
 	for {
 		fmt.Print("\n> ")
 		userInput, _ := reader.ReadString('\n')
 		userInput = strings.TrimSpace(userInput)
 		
+		if userInput == "/set" || strings.HasPrefix(userInput, "/set ") {
+			setGenerationParam(&sessionParams, apiConfig.GenerationParams, strings.Fields(userInput)[1:])
+			continue
+		}
+		if userInput == "/compare" || strings.HasPrefix(userInput, "/compare ") {
+			args := strings.Fields(userInput)
+			if len(args) < 3 {
+				utils.ShowWarning("用法: /compare <配置1,配置2,...> <问题>")
+				continue
+			}
+			if err := costs.CheckBudget(); err != nil {
+				utils.ShowWarning(err.Error())
+				continue
+			}
+			question := strings.TrimSpace(strings.TrimPrefix(userInput, "/compare"))
+			question = strings.TrimSpace(strings.TrimPrefix(question, args[1]))
+			
+			reqCtx, done := interrupts.begin(ctx)
+			reqCtx = providers.WithGenerationParams(reqCtx, sessionParams)
+			messages := buildFullPrompt(reqCtx, question, stateMgr, tokenMgr, index)
+			attachImages(messages, append(pendingImages, imageReferences(question)...))
+			pendingImages = nil
+			
+			fmt.Printf("正在请求 %s ...\n", args[1])
+			results := compareProfiles(reqCtx, cfgMgr, strings.Split(args[1], ","), messages)
+			done()
+			for i := range results {
+				if results[i].Err == nil {
+					results[i].Response.Usage = tokenMgr.EstimateUsage(results[i].Response.Usage, messages, results[i].Response.Content)
+				}
+				results[i].Cost = recordCost(costs, results[i].Model, results[i].Response.Usage)
+			}
+			showComparison(results)
+			
+			// 选中的回答写入对话历史，其操作经过正常的确认流程执行
+			if chosen := chooseComparison(results); chosen != nil {
+				recordTurn(tokenMgr, question, chosen.Response.Content, nil)
+				for _, op := range chosen.Operations {
+					if err := processOperation(ctx, op, fileMgr, stateMgr, tokenMgr); err != nil && !errors.Is(err, errOperationCanceled) {
+						utils.ShowError("操作执行失败", err)
+					}
+				}
+			}
+			utils.DisplayTokenUsage(tokenMgr.GetTokenUsage())
+			color.HiBlack("费用: 本次会话 %s · 累计 %s", costs.SessionSummary(), costs.TotalSummary())
+			continue
+		}
+		if userInput == "/image" || strings.HasPrefix(userInput, "/image ") {
+			pendingImages = handleImageCommand(pendingImages, strings.TrimSpace(strings.TrimPrefix(userInput, "/image")))
+			continue
+		}
+		
 		switch userInput {
 		case "":
 			continue
@@ -96,51 +215,229 @@ func main() {
 			stateMgr.ScanInitialDirectory(".")
 			utils.ShowSuccess("目录状态已刷新")
 			continue
+		case "/reasoning":
+			showReasoning(lastReasoning, tokenMgr)
+			continue
+		case "/models":
+			reqCtx, done := interrupts.begin(ctx)
+			showModels(reqCtx, provider)
+			done()
+			continue
 		}
 		
-		// 构建完整提示
-		prompt := buildFullPrompt(userInput, stateMgr)
+		// 会话花费达到上限后不再发送请求
+		if err := costs.CheckBudget(); err != nil {
+			utils.ShowWarning(err.Error())
+			continue
+		}
 		
-		// 发送请求
-		response, err := provider.SendRequest(ctx, prompt)
+		// 发送请求，Ctrl-C 仅取消本次请求
+		reqCtx, done := interrupts.begin(ctx)
+		reqCtx = providers.WithRetryNotifier(reqCtx, func(ev providers.RetryEvent) {
+			utils.ShowRetry(ev.Attempt, ev.MaxRetries, ev.Reason, ev.Wait)
+		})
+		reqCtx = providers.WithRateLimitNotifier(reqCtx, func(ev providers.RateLimitEvent) {
+			color.HiBlack("客户端限流（%s），%.1f秒后发送", ev.Reason, ev.Wait.Seconds())
+		})
+		reqCtx = providers.WithFallbackNotifier(reqCtx, func(ev providers.FallbackEvent) {
+			utils.ShowWarning(fmt.Sprintf("配置 %s 请求失败，切换到 %s: %v", ev.From, ev.To, ev.Err))
+		})
+		reqCtx = providers.WithGenerationParams(reqCtx, sessionParams)
+		
+		// 构建完整对话
+		messages := buildFullPrompt(reqCtx, userInput, stateMgr, tokenMgr, index)
+		
+		// 构建对话时的嵌入请求不计入，只统计对话请求是否命中缓存
+		var cacheHits, cacheMisses int
+		reqCtx = providers.WithCacheNotifier(reqCtx, func(ev providers.CacheEvent) {
+			if ev.Hit {
+				cacheHits++
+			} else {
+				cacheMisses++
+			}
+		})
+		
+		// 图片只随本条消息发送，不进入对话历史
+		attachImages(messages, append(pendingImages, imageReferences(userInput)...))
+		pendingImages = nil
+		
+		toolProvider, useTools := provider.(types.ToolCallingProvider)
+		useTools = useTools && provider.SupportsFeature(types.CapTools)
+		printer := newStreamPrinter(useTools)
+		reqCtx = providers.WithReasoningHandler(reqCtx, printer.Reasoning)
+		var response types.Response
+		var usage types.Usage
+		var executed []types.FileOperation
+		var structured bool
+		if useTools {
+			// 原生工具调用：操作在对话过程中执行，回复文字流式输出
+			var turn toolTurn
+			turn, err = runToolTurn(reqCtx, toolProvider, messages, printer, fileMgr, stateMgr, tokenMgr)
+			response, messages, executed = turn.Response, turn.Messages, turn.Executed
+			usage = turn.Usage
+		} else {
+			// 文本协议：流式输出说明文字，操作指令以 JSON 返回；
+			// 支持结构化输出时整个回复是 JSON 对象，说明文字在解析后显示
+			messages, structured = requestReplyFormat(provider, messages)
+			if structured {
+				response, err = providers.StreamStructured(reqCtx, provider, messages, operations.ReplyFormat(), printer.Write)
+			} else {
+				response, err = providers.Stream(reqCtx, provider, messages, printer.Write)
+			}
+			usage = response.Usage
+			if err == nil {
+				usage = tokenMgr.EstimateUsage(usage, messages, response.Content)
+			}
+		}
+		printer.Finish()
+		canceled := errors.Is(reqCtx.Err(), context.Canceled)
+		done()
+		
+		// 工具调用中途失败时之前的请求已产生费用，先计费再处理错误；全部命中缓存时不计费
+		model := response.Model
+		if model == "" {
+			model = provider.GetModel()
+		}
+		cached := cacheHits > 0 && cacheMisses == 0
+		var requestCost string
+		if cached {
+			requestCost = "0（缓存命中）"
+		} else {
+			requestCost = recordCost(costs, model, usage)
+		}
 		if err != nil {
-			utils.ShowError("AI请求失败", err)
+			if canceled {
+				utils.ShowWarning("请求已取消")
+			} else {
+				utils.ShowError("AI请求失败", err)
+			}
+			continue
+		}
+		
+		// 思考过程只供查看，不进入对话历史，也不参与操作指令解析
+		lastReasoning = response.Reasoning
+		
+		// 回退链中由备用供应商回答时注明来源，该供应商不支持结构化输出时按文本解析
+		if chain, ok := provider.(*providers.FallbackProvider); ok && !chain.IsPrimary() {
+			profile, answered := chain.Answered()
+			color.HiBlack("（由 %s 回答: %s / %s）", profile, answered.GetName(), answered.GetModel())
+			structured = structured && providers.SupportsStructuredOutput(answered)
+		}
+		
+		// 记录对话历史，后续轮次会重新发送；供应商报告了用量时以实际值校准
+		recordTurn(tokenMgr, userInput, response.Content, executed)
+		if err := tokenMgr.Reconcile(response.Usage, messages, response.Content); err != nil {
+			utils.ShowWarning(err.Error())
+		}
+		
+		if useTools {
+			utils.DisplayTokenUsage(tokenMgr.GetTokenUsage())
+			utils.DisplayRequestUsage(tokenMgr.LastUsage(), cached)
+			utils.DisplayCost(requestCost, costs.SessionSummary(), costs.TotalSummary())
 			continue
 		}
 		
 		// 解析操作指令
-		operations, err := parseOperations(response)
+		message, operations, err := parseOperations(response.Content, structured)
 		if err != nil {
 			utils.ShowError("解析操作指令失败", err)
 			continue
 		}
+		if message != "" {
+			fmt.Println(message)
+		}
 		
 		// 处理操作指令
 		for _, op := range operations {
-			if err := processOperation(ctx, op, fileMgr, stateMgr, tokenMgr); err != nil {
+			if err := processOperation(ctx, op, fileMgr, stateMgr, tokenMgr); err != nil && !errors.Is(err, errOperationCanceled) {
 				utils.ShowError("操作执行失败", err)
 			}
 		}
 		
 		// 更新Token状态
-		tokenMgr.AddRecord(userInput, operations)
 		utils.DisplayTokenUsage(tokenMgr.GetTokenUsage())
+		utils.DisplayRequestUsage(tokenMgr.LastUsage(), cached)
+		utils.DisplayCost(requestCost, costs.SessionSummary(), costs.TotalSummary())
+	}
+}
+
+// recordCost 计入一次请求的费用，返回用于显示的金额，未配置该模型的价格时返回空字符串。
+// 按估算用量计算的费用注明估算
+func recordCost(costs *state.CostTracker, model string, usage types.Usage) string {
+	cost, err := costs.Record(model, usage)
+	if err != nil {
+		utils.ShowWarning("保存累计花费失败: " + err.Error())
 	}
+	if !cost.Priced {
+		return ""
+	}
+	if cost.Estimated {
+		return state.FormatMoney(cost.Amount, cost.Currency) + "（估算）"
+	}
+	return state.FormatMoney(cost.Amount, cost.Currency)
 }
 
-func buildFullPrompt(userInput string, stateMgr *state.ProjectState) string {
-	stateJSON, _ := json.Marshal(stateMgr.GetCurrentState())
+// createProvider 创建配置对应的供应商；配置声明了 fallbacks 时，
+// 按顺序创建备用供应商并组成回退链
+func createProvider(cfgMgr *config.ConfigManager, apiConfig types.APIConfig) (types.AIProvider, error) {
+	primary, err := providers.CreateProvider(apiConfig)
+	if err != nil || len(apiConfig.Fallbacks) == 0 {
+		return primary, err
+	}
+	
+	members := []providers.FallbackMember{{Profile: apiConfig.Name, Provider: primary}}
+	for _, name := range apiConfig.Fallbacks {
+		fallbackConfig, err := cfgMgr.GetProfile(name)
+		if err != nil {
+			return nil, fmt.Errorf("回退配置无效: %v", err)
+		}
+		fallback, err := providers.CreateProvider(fallbackConfig)
+		if err != nil {
+			return nil, fmt.Errorf("创建回退供应商 %s 失败: %v", name, err)
+		}
+		members = append(members, providers.FallbackMember{Profile: name, Provider: fallback})
+	}
 	
-	// 添加系统消息
-	prompt := fmt.Sprintf(`
+	return providers.NewFallbackProvider(members)
+}
+
+// buildFullPrompt 构建本轮请求的完整对话：
+// 描述项目状态的系统消息 + 历史对话 + 当前用户输入
+func buildFullPrompt(ctx context.Context, userInput string, stateMgr *state.ProjectState,
+	tokenMgr *state.TokenManager, index *state.VectorIndex) []types.Message {
+	
+	systemPrompt := buildSystemPrompt(stateMgr)
+	if index != nil {
+		systemPrompt += relevantSnippets(ctx, index, userInput, stateMgr)
+	}
+	
+	messages := []types.Message{
+		{Role: types.RoleSystem, Content: systemPrompt},
+	}
+	messages = append(messages, tokenMgr.Messages()...)
+	messages = append(messages, types.Message{Role: types.RoleUser, Content: userInput})
+	return messages
+}
+
+// requestReplyFormat 为支持结构化输出的供应商附加 operations.Reply 的格式说明，
+// 返回要发送的对话以及是否应以 operations.ReplyFormat() 发送；不支持时原样返回
+func requestReplyFormat(provider types.AIProvider, messages []types.Message) ([]types.Message, bool) {
+	if !providers.SupportsStructuredOutput(provider) {
+		return messages, false
+	}
+	formatted := append([]types.Message(nil), messages...)
+	formatted[0].Content += operations.ReplyInstructions
+	return formatted, true
+}
+
+// buildSystemPrompt 描述当前项目状态，每轮重新生成以反映最新的文件内容
+func buildSystemPrompt(stateMgr *state.ProjectState) string {
+	prompt := fmt.Sprintf(`你是一个智能代码助手。
 系统信息:
 - 当前目录: %s
 - 扫描深度: %d层
 - 文件总数: %d
 
-用户请求:
-%s
-
 项目结构:
 %s
 
@@ -148,7 +445,6 @@ func buildFullPrompt(userInput string, stateMgr *state.ProjectState) string {
 		stateMgr.GetCWD(),
 		stateMgr.GetMaxDepth(),
 		len(stateMgr.GetFileStates()),
-		userInput,
 		stateMgr.GetDirectoryTree(),
 	)
 	
@@ -164,21 +460,100 @@ func buildFullPrompt(userInput string, stateMgr *state.ProjectState) string {
 	return prompt
 }
 
-func parseOperations(response string) ([]types.FileOperation, error) {
-	// 在实际实现中，这里需要解析AI返回的JSON结构
-	// 以下是简化实现
+// openIndex 打开供应商向量模型对应的项目索引
+func openIndex(provider types.AIProvider, stateMgr *state.ProjectState) (*state.VectorIndex, error) {
+	embedder, ok := provider.(types.Embedder)
+	if !ok {
+		return nil, fmt.Errorf("%s 不支持 embeddings", provider.GetName())
+	}
+	return state.NewVectorIndex(state.IndexPath(stateMgr.GetCWD(), embedder.EmbeddingInfo().Model), embedder)
+}
+
+// relevantSnippets 更新索引并检索与问题相关的代码片段，已加载内容的文件不重复提供。
+// 索引出错时只提示，不影响本轮对话
+func relevantSnippets(ctx context.Context, index *state.VectorIndex, query string, stateMgr *state.ProjectState) string {
+	files := stateMgr.GetFileStates()
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
 	
-	var operations []types.FileOperation
+	updated, err := index.Update(ctx, stateMgr.GetCWD(), paths)
+	if err != nil {
+		utils.ShowWarning("更新向量索引失败: " + err.Error())
+		return ""
+	}
+	if updated > 0 {
+		color.HiBlack("向量索引已更新 %d 个文件（共 %d 个）", updated, index.Len())
+	}
 	
-	// 假设AI返回的操作是JSON格式
-	err := json.Unmarshal([]byte(response), &operations)
+	hits, err := index.Search(ctx, query, maxSnippets*2)
 	if err != nil {
-		return nil, fmt.Errorf("解析操作失败: %v", err)
+		utils.ShowWarning("检索向量索引失败: " + err.Error())
+		return ""
+	}
+	
+	var snippets strings.Builder
+	count := 0
+	for _, hit := range hits {
+		if count == maxSnippets {
+			break
+		}
+		if fileState, ok := files[hit.Path]; ok && fileState.Content != "" {
+			continue
+		}
+		snippets.WriteString(fmt.Sprintf("\n\n文件[%s:%d-%d]:\n```\n%s\n```", hit.Path, hit.StartLine, hit.EndLine, hit.Text))
+		count++
+	}
+	if count == 0 {
+		return ""
+	}
+	return "\n\n与当前问题相关的代码片段（按相关度排序）:" + snippets.String()
+}
+
+// recordTurn 将本轮的用户输入、通过工具调用执行的操作与AI回复写入对话历史
+func recordTurn(tokenMgr *state.TokenManager, userInput, response string, executed []types.FileOperation) {
+	records := []*state.ConversationRecord{
+		{Role: types.RoleUser, Content: userInput},
+	}
+	for _, op := range executed {
+		records = append(records, &state.ConversationRecord{
+			Role:      types.RoleAssistant,
+			Content:   fmt.Sprintf("已执行操作: %s %s", op.Action, op.Path),
+			Operation: op,
+		})
+	}
+	records = append(records, &state.ConversationRecord{Role: types.RoleAssistant, Content: response})
+	
+	for _, rec := range records {
+		if err := tokenMgr.AddRecord(rec); err != nil {
+			utils.ShowWarning(err.Error())
+		}
+	}
+}
+
+// parseOperations 解析回复中的操作指令。structured 表示回复受结构化输出约束，
+// 按 operations.Reply 解析并返回其中的说明文字；否则从自由文本中提取操作，说明文字已在流式输出时显示
+func parseOperations(response string, structured bool) (string, []types.FileOperation, error) {
+	if structured {
+		reply, err := operations.ParseReply(response)
+		if err != nil {
+			return "", nil, fmt.Errorf("解析操作失败: %v", err)
+		}
+		return reply.Message, reply.Operations, nil
 	}
 	
-	return operations, nil
+	// 回退链中不支持结构化输出的供应商也可能按提示词回复 Reply 对象
+	reply, err := operations.ExtractReply(response)
+	if err != nil {
+		return "", nil, fmt.Errorf("解析操作失败: %v", err)
+	}
+	return reply.Message, reply.Operations, nil
 }
 
+// errOperationCanceled 表示用户拒绝了操作
+var errOperationCanceled = errors.New("用户取消了操作")
+
 func processOperation(ctx context.Context, op types.FileOperation, fm operations.FileManager, 
 	stateMgr *state.ProjectState, tokenMgr *state.TokenManager) error {
 	
@@ -188,7 +563,7 @@ func processOperation(ctx context.Context, op types.FileOperation, fm operations
 		
 	case "write", "create":
 		if !utils.UserApproval(op, &fm) {
-			return nil // 用户取消操作
+			return errOperationCanceled
 		}
 		return handleWriteOperation(op, fm, stateMgr)
 		
@@ -255,7 +630,7 @@ func handleWriteOperation(op types.FileOperation, fm operations.FileManager, sta
 func handleScanOperation(op types.FileOperation, stateMgr *state.ProjectState) error {
 	utils.ShowWarning(fmt.Sprintf("AI请求扫描目录: %s", op.Path))
 	if !utils.GetUserConfirmation("确认扫描? (y/n) > ") {
-		return nil
+		return errOperationCanceled
 	}
 	
 	// 执行扫描
@@ -267,11 +642,116 @@ func handleScanOperation(op types.FileOperation, stateMgr *state.ProjectState) e
 	return nil
 }
 
+// showModels 列出供应商可用的模型及其上下文窗口与能力，当前模型以 * 标出。
+// 供应商不支持列出模型时只显示当前模型的内置信息
+func showModels(ctx context.Context, provider types.AIProvider) {
+	current := provider.GetModel()
+	
+	var models []types.ModelInfo
+	if lister, ok := provider.(types.ModelLister); ok {
+		var err error
+		models, err = lister.ListModels(ctx)
+		if err != nil {
+			utils.ShowError("获取模型列表失败", err)
+			return
+		}
+	} else {
+		info, _ := providers.LookupModel(current)
+		models = []types.ModelInfo{info}
+	}
+	
+	fmt.Printf("\n%s 可用模型:\n", provider.GetName())
+	for _, m := range models {
+		marker := " "
+		if m.ID == current {
+			marker = "*"
+		}
+		window := "未知"
+		if m.ContextWindow > 0 {
+			window = fmt.Sprintf("%d", m.ContextWindow)
+		}
+		caps := make([]string, 0, len(m.Capabilities))
+		for _, c := range m.Capabilities {
+			caps = append(caps, string(c))
+		}
+		fmt.Printf("%s %-40s 上下文 %-8s %s\n", marker, m.ID, window, strings.Join(caps, ","))
+	}
+}
+
+// modelInfo 返回当前模型的上下文窗口等信息：优先使用供应商报告的值，
+// 供应商不支持列出模型、请求失败或未报告上下文窗口时查内置表
+func modelInfo(ctx context.Context, provider types.AIProvider) (types.ModelInfo, bool) {
+	current := provider.GetModel()
+	if lister, ok := provider.(types.ModelLister); ok {
+		// 启动时调用，不因模型列表接口缓慢而长时间等待
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if models, err := lister.ListModels(ctx); err == nil {
+			for _, m := range models {
+				if m.ID == current && m.ContextWindow > 0 {
+					return m, true
+				}
+			}
+		}
+	}
+	info, ok := providers.LookupModel(current)
+	return info, ok && info.ContextWindow > 0
+}
+
+// setGenerationParam 处理 /set 命令：无参数时显示当前生效的生成参数，
+// "/set name value" 设置会话级参数，"/set name default" 恢复为配置中的值
+func setGenerationParam(session *types.GenerationParams, profile types.GenerationParams, args []string) {
+	current := func() string {
+		if s := providers.FormatGenerationParams(profile.Merge(*session)); s != "" {
+			return s
+		}
+		return "(供应商默认值)"
+	}
+	
+	switch len(args) {
+	case 0:
+		fmt.Printf("生成参数: %s\n", current())
+		fmt.Printf("可设置: %s\n", strings.Join(providers.GenerationParamNames, ", "))
+		return
+	case 1:
+		utils.ShowWarning("用法: /set <参数> <值>，值为 default 时恢复配置中的设置")
+		return
+	}
+	
+	name, value := args[0], strings.Join(args[1:], " ")
+	if err := providers.SetGenerationParam(session, name, value); err != nil {
+		utils.ShowError("设置参数失败", err)
+		return
+	}
+	utils.ShowSuccess("生成参数: " + current())
+}
+
+// showReasoning 展开最近一次回复的思考过程
+func showReasoning(reasoning string, tokenMgr *state.TokenManager) {
+	if reasoning == "" {
+		fmt.Println("最近一次回复没有思考过程")
+		return
+	}
+	
+	fmt.Println()
+	color.HiBlack("💭 思考过程:")
+	color.HiBlack("%s", reasoning)
+	if total := tokenMgr.ReasoningTokens(); total > 0 {
+		color.HiBlack("（本次会话累计思考 %d tokens）", total)
+	}
+}
+
 func printHelp() {
 	fmt.Println("\n可用命令:")
 	fmt.Println("  /exit       - 退出程序")
 	fmt.Println("  /help       - 显示此帮助信息")
 	fmt.Println("  /reload     - 重新扫描当前目录")
+	fmt.Println("  /models     - 列出可用模型及其上下文窗口")
+	fmt.Println("  /reasoning  - 展开最近一次回复的思考过程")
+	fmt.Println("  /set        - 查看或设置生成参数，如 /set temperature 0、/set seed 42")
+	fmt.Println("  /compare    - 并发询问多个配置并比较操作，如 /compare deepseek,siliconflow 修复登录错误")
+	fmt.Println("  /image      - 附加图片随下一条消息发送，如 /image ui.png；也可在消息中写 @ui.png")
+	fmt.Println("  Ctrl-C      - 取消正在进行的请求")
 	fmt.Println()
 	fmt.Println("操作支持:")
 	fmt.Println("  AI可执行读取(read)、写入(write)、创建(create)文件和扫描(scan)目录操作")
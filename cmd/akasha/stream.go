@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+	
+	"github.com/fatih/color"
+)
+
+// streamPrinter 实时打印流式回复中的说明文字，
+// 一旦某行以 JSON 或代码围栏开头便视为操作指令，停止打印并等待完整回复；
+// 原生工具调用的回复不含操作指令，plain 为 true 时原样打印全部文字。
+// 推理模型的思考过程折叠为一行暗色进度，完整内容可通过 /reasoning 查看
+type streamPrinter struct {
+	plain       bool
+	printed     bool
+	atLineStart bool
+	pendingWS   strings.Builder
+	inOps       bool
+	
+	reasoning     int  // 已收到的思考过程字数
+	reasoningOpen bool // 思考进度行尚未收起
+}
+
+func newStreamPrinter(plain bool) *streamPrinter {
+	return &streamPrinter{plain: plain, atLineStart: true}
+}
+
+// Reasoning 处理一段思考过程，只在同一行刷新字数
+func (sp *streamPrinter) Reasoning(chunk string) {
+	if chunk == "" {
+		return
+	}
+	if !sp.reasoningOpen && sp.reasoning == 0 {
+		fmt.Println()
+	}
+	sp.reasoningOpen = true
+	sp.reasoning += utf8.RuneCountInString(chunk)
+	fmt.Print("\r" + color.HiBlackString("💭 思考中… %d 字", sp.reasoning))
+}
+
+// closeReasoning 将思考进度行收起为摘要
+func (sp *streamPrinter) closeReasoning() {
+	if !sp.reasoningOpen {
+		return
+	}
+	sp.reasoningOpen = false
+	fmt.Print("\r\033[K")
+	color.HiBlack("💭 已思考 %d 字（输入 /reasoning 展开）", sp.reasoning)
+}
+
+// Write 处理一段流式文本
+func (sp *streamPrinter) Write(chunk string) {
+	if chunk != "" {
+		sp.closeReasoning()
+	}
+	for _, r := range chunk {
+		if sp.inOps {
+			return
+		}
+		
+		if sp.atLineStart && !sp.plain {
+			switch r {
+			case ' ', '\t':
+				sp.pendingWS.WriteRune(r)
+				continue
+			case '[', '{', '`':
+				sp.inOps = true
+				if sp.printed {
+					fmt.Println()
+				}
+				color.HiBlack("… 正在接收操作指令")
+				return
+			}
+		}
+		
+		if !sp.printed {
+			if sp.reasoning == 0 {
+				fmt.Println()
+			}
+			sp.printed = true
+		}
+		fmt.Print(sp.pendingWS.String())
+		sp.pendingWS.Reset()
+		fmt.Print(string(r))
+		sp.atLineStart = r == '\n'
+	}
+}
+
+// Finish 结束本次输出，保证后续提示从新行开始。
+// 工具调用的各轮之间也会调用，之后可以继续输出
+func (sp *streamPrinter) Finish() {
+	sp.closeReasoning()
+	if sp.printed && !sp.inOps && !sp.atLineStart {
+		fmt.Println()
+		sp.atLineStart = true
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	
+	"github.com/yantianyv/AkashaTerminal/internal/operations"
+	"github.com/yantianyv/AkashaTerminal/internal/providers"
+	"github.com/yantianyv/AkashaTerminal/internal/state"
+	"github.com/yantianyv/AkashaTerminal/internal/utils"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// maxToolRounds 限制单轮对话中模型连续调用工具的次数
+const maxToolRounds = 8
+
+// toolTurn 是一轮工具调用对话的结果
+type toolTurn struct {
+	Response types.Response        // 不含工具调用的最终回复
+	Messages []types.Message       // 最后一次请求发送的对话，包括工具调用结果
+	Executed []types.FileOperation // 成功执行的操作
+	Usage    types.Usage           // 各次请求的用量之和，用于计费
+}
+
+// runToolTurn 以原生工具调用完成一轮对话：逐个执行模型请求的工具调用，
+// 结果作为 tool 消息回传，直到模型给出不含工具调用的最终回复。各轮回复中的文字流式交给 printer
+func runToolTurn(ctx context.Context, provider types.ToolCallingProvider, messages []types.Message, printer *streamPrinter,
+	fm operations.FileManager, stateMgr *state.ProjectState, tokenMgr *state.TokenManager) (toolTurn, error) {
+	
+	tools := operations.ToolDefinitions()
+	turn := toolTurn{Messages: messages}
+	
+	for round := 0; round < maxToolRounds; round++ {
+		reply, err := providers.StreamWithTools(ctx, provider, turn.Messages, tools, printer.Write)
+		if err != nil {
+			return turn, err
+		}
+		// 未报告用量的轮次按本轮发送的对话估算，工具调用的每轮请求都会计费
+		turn.Usage = turn.Usage.Add(tokenMgr.EstimateUsage(reply.Usage, turn.Messages, reply.Content))
+		
+		if len(reply.ToolCalls) == 0 {
+			turn.Response = reply
+			return turn, nil
+		}
+		
+		// 执行工具时会显示确认提示，先结束本轮文字的输出
+		printer.Finish()
+		turn.Messages = append(turn.Messages, reply.Message())
+		for _, call := range reply.ToolCalls {
+			op, result := executeToolCall(ctx, call, fm, stateMgr, tokenMgr)
+			if op != nil {
+				turn.Executed = append(turn.Executed, *op)
+			}
+			turn.Messages = append(turn.Messages, types.Message{
+				Role:       types.RoleTool,
+				ToolCallID: call.ID,
+				Content:    result,
+			})
+		}
+	}
+	
+	return turn, fmt.Errorf("工具调用轮数超过上限 (%d)", maxToolRounds)
+}
+
+// executeToolCall 执行一次工具调用，返回成功执行的操作（失败或取消时为 nil）及回传给模型的结果
+func executeToolCall(ctx context.Context, call types.ToolCall, fm operations.FileManager,
+	stateMgr *state.ProjectState, tokenMgr *state.TokenManager) (*types.FileOperation, string) {
+	
+	op, err := operations.OperationFromToolCall(call)
+	if err != nil {
+		utils.ShowError("工具调用无效", err)
+		return nil, "错误: " + err.Error()
+	}
+	
+	if err := processOperation(ctx, op, fm, stateMgr, tokenMgr); err != nil {
+		if errors.Is(err, errOperationCanceled) {
+			return nil, "用户拒绝了该操作"
+		}
+		utils.ShowError("操作执行失败", err)
+		return nil, "错误: " + err.Error()
+	}
+	
+	return &op, toolResult(op, stateMgr)
+}
+
+// toolResult 生成回传给模型的操作结果
+func toolResult(op types.FileOperation, stateMgr *state.ProjectState) string {
+	switch op.Action {
+	case "read":
+		return stateMgr.GetFileStates()[op.Path].Content
+	case "scan":
+		return fmt.Sprintf("已扫描目录 %s:\n%s", op.Path, strings.Join(stateMgr.GetScannedFiles(op.Path), "\n"))
+	case "create":
+		return fmt.Sprintf("已创建文件 %s", op.Path)
+	default:
+		return fmt.Sprintf("已更新文件 %s", op.Path)
+	}
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	
+	"github.com/spf13/cobra"
+	"github.com/yantianyv/AkashaTerminal/internal/config"
+	"github.com/yantianyv/AkashaTerminal/internal/providers"
+	"github.com/yantianyv/AkashaTerminal/internal/utils"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+func NewConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "管理 API 配置",
+	}
+	
+	cmd.AddCommand(&cobra.Command{
+		Use:   "providers",
+		Short: "列出可用的供应商类型",
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, reg := range providers.Registered() {
+				fmt.Printf("  %-12s %s\n", reg.Name, reg.Description)
+			}
+		},
+	})
+	
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "列出已保存的配置",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfgMgr := config.NewConfigManager()
+			if err := cfgMgr.Load(); err != nil {
+				return err
+			}
+			for name, profile := range cfgMgr.Profiles {
+				marker := " "
+				if name == cfgMgr.Default {
+					marker = "*"
+				}
+				fmt.Printf("%s %-16s %-12s %s\n", marker, name, profile.Provider, profile.Model)
+			}
+			return nil
+		},
+	})
+	
+	cmd.AddCommand(&cobra.Command{
+		Use:   "add [name]",
+		Short: "通过向导添加配置",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return runConfigWizard(name)
+		},
+	})
+	
+	return cmd
+}
+
+// runConfigWizard 根据注册表中的供应商信息交互式地创建配置
+func runConfigWizard(name string) error {
+	cfgMgr := config.NewConfigManager()
+	if err := cfgMgr.Load(); err != nil {
+		return err
+	}
+	
+	if name == "" {
+		name = utils.UserPrompt("配置名称 > ")
+	}
+	if name == "" {
+		return fmt.Errorf("配置名称不能为空")
+	}
+	
+	regs := providers.Registered()
+	fmt.Println("\n可用的供应商:")
+	for i, reg := range regs {
+		fmt.Printf("  %d) %-12s %s\n", i+1, reg.Name, reg.Description)
+	}
+	
+	choice := utils.UserPrompt("选择供应商 (编号或名称) > ")
+	reg, ok := providers.Lookup(choice)
+	if idx, err := strconv.Atoi(choice); err == nil && idx >= 1 && idx <= len(regs) {
+		reg, ok = regs[idx-1], true
+	}
+	if !ok {
+		return fmt.Errorf("未知的供应商: %s", choice)
+	}
+	
+	// 按注册的字段逐项询问，留空表示不设置
+	values := map[string]interface{}{"provider": reg.Name}
+	for _, field := range reg.Fields {
+		input := utils.UserPrompt(fmt.Sprintf("%s > ", field))
+		if input == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(input); err == nil && isNumericField(field) {
+			values[field] = n
+		} else {
+			values[field] = input
+		}
+	}
+	
+	var profile types.APIConfig
+	data, _ := json.Marshal(values)
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return err
+	}
+	
+	if err := providers.ValidateConfig(profile); err != nil {
+		return fmt.Errorf("配置无效: %v", err)
+	}
+	
+	if cfgMgr.Profiles == nil {
+		cfgMgr.Profiles = make(map[string]types.APIConfig)
+	}
+	if err := cfgMgr.AddProfile(name, profile); err != nil {
+		return fmt.Errorf("保存配置失败: %v", err)
+	}
+	if cfgMgr.Default == "" {
+		if err := cfgMgr.SetDefault(name); err != nil {
+			return fmt.Errorf("设置默认配置失败: %v", err)
+		}
+	}
+	
+	utils.ShowSuccess(fmt.Sprintf("已保存配置: %s (%s)", name, reg.Name))
+	return nil
+}
+
+// isNumericField 判断 APIConfig 中的字段是否为整数类型
+func isNumericField(field string) bool {
+	return strings.HasSuffix(field, "tokens") || field == "timeout"
+}
@@ -18,6 +18,8 @@ type ConfigManager struct {
 	Path         string
 	Default      string
 	Profiles     map[string]types.APIConfig
+	Prices       map[string]types.ModelPrice // 按模型覆盖内置的价格表
+	Cache        *types.CacheConfig          // 本地响应缓存，未配置时关闭
 }
 
 func NewConfigManager() *ConfigManager {
@@ -50,6 +52,8 @@ func (cm *ConfigManager) Load() error {
 	var configData struct {
 		DefaultProfile string                 `json:"default_profile"`
 		Profiles       map[string]types.APIConfig `json:"profiles"`
+		Prices         map[string]types.ModelPrice `json:"prices,omitempty"`
+		Cache          *types.CacheConfig          `json:"cache,omitempty"`
 	}
 	
 	if err := json.Unmarshal(data, &configData); err != nil {
@@ -58,6 +62,8 @@ func (cm *ConfigManager) Load() error {
 	
 	cm.Default = configData.DefaultProfile
 	cm.Profiles = configData.Profiles
+	cm.Prices = configData.Prices
+	cm.Cache = configData.Cache
 	return nil
 }
 
@@ -66,9 +72,13 @@ func (cm *ConfigManager) Save() error {
 	configData := struct {
 		DefaultProfile string                 `json:"default_profile"`
 		Profiles       map[string]types.APIConfig `json:"profiles"`
+		Prices         map[string]types.ModelPrice `json:"prices,omitempty"`
+		Cache          *types.CacheConfig          `json:"cache,omitempty"`
 	}{
 		DefaultProfile: cm.Default,
 		Profiles:       cm.Profiles,
+		Prices:         cm.Prices,
+		Cache:          cm.Cache,
 	}
 	
 	data, err := json.MarshalIndent(configData, "", "  ")
@@ -80,15 +90,15 @@ func (cm *ConfigManager) Save() error {
 }
 
 // AddProfile 添加新配置
-func (cm *ConfigManager) AddProfile(name string, config types.APIConfig) {
+func (cm *ConfigManager) AddProfile(name string, config types.APIConfig) error {
 	cm.Profiles[name] = config
-	cm.Save()
+	return cm.Save()
 }
 
 // DeleteProfile 删除配置
-func (cm *ConfigManager) DeleteProfile(name string) {
+func (cm *ConfigManager) DeleteProfile(name string) error {
 	delete(cm.Profiles, name)
-	cm.Save()
+	return cm.Save()
 }
 
 // SetDefault 设置默认配置
@@ -103,6 +113,7 @@ func (cm *ConfigManager) SetDefault(name string) error {
 // GetProfile 获取指定配置
 func (cm *ConfigManager) GetProfile(name string) (types.APIConfig, error) {
 	if config, exists := cm.Profiles[name]; exists {
+		config.Name = name
 		return config, nil
 	}
 	return types.APIConfig{}, fmt.Errorf("profile %s not found", name)
@@ -0,0 +1,83 @@
+// Package fileutil 提供多个进程共用状态文件时所需的原子写入与文件锁
+package fileutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// lockStale 锁文件超过该时间未释放时视为持有的进程已退出
+	lockStale = 10 * time.Second
+	lockPoll  = 20 * time.Millisecond
+)
+
+// WriteAtomic 先写入同目录下的临时文件再替换 path，中断时不会留下半个文件。
+// 临时文件名各不相同，多个进程同时写入同一路径时互不覆盖，最终保留最后替换的内容
+func WriteAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// Lock 以独占创建的方式获取锁文件，返回释放函数，ctx 结束时放弃等待。
+// 锁文件长时间未释放时（持有的进程异常退出）将其删除后重新获取
+func Lock(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+		if err := SleepContext(ctx, lockPoll); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SleepContext 等待 d，ctx 取消时提前返回
+func SleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
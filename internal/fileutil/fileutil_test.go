@@ -0,0 +1,116 @@
+package fileutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	
+	// 并发写入同一路径，最终内容是某一次完整的写入，不留下临时文件
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- WriteAtomic(path, []byte(fmt.Sprintf("writer-%02d", i)), 0600)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if _, err := fmt.Sscanf(string(data), "writer-%02d", &n); err != nil || len(data) != len("writer-00") {
+		t.Errorf("content = %q", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the target file", len(entries))
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, %v", info.Mode(), err)
+	}
+	
+	// 目录不存在时报错，不创建文件
+	if err := WriteAtomic(filepath.Join(dir, "missing", "x.json"), nil, 0600); err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.lock")
+	unlock, err := Lock(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	// 锁被持有时等待，直到上下文结束
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := Lock(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock while held = %v, want deadline exceeded", err)
+	}
+	
+	// 释放后可以立即获取
+	unlock()
+	unlock, err = Lock(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	// 持有者异常退出留下的过期锁会被接管
+	old := time.Now().Add(-2 * lockStale)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := Lock(ctx, path); err != nil {
+		t.Fatalf("Lock with stale lock = %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file left after unlock: %v", err)
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		d    time.Duration
+		want error
+	}{
+		{"elapsed", context.Background(), time.Millisecond, nil},
+		{"zero", context.Background(), 0, nil},
+		{"canceled", ctx, time.Hour, context.Canceled},
+		{"canceled zero", ctx, 0, context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SleepContext(tt.ctx, tt.d); !errors.Is(err, tt.want) {
+				t.Errorf("SleepContext = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
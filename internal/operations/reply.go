@@ -0,0 +1,164 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// Reply 是结构化输出的回复：给用户的说明与要执行的操作
+type Reply struct {
+	Message    string                `json:"message"`
+	Operations []types.FileOperation `json:"operations"`
+}
+
+// ReplyInstructions 附加在系统提示词中说明回复格式。
+// 只支持 JSON 模式的供应商依靠它约定字段，部分供应商还要求提示词中出现 "json"
+const ReplyInstructions = `
+
+回复格式: 只输出一个 json 对象，不要输出其他文字，例如:
+{"message": "给用户的说明", "operations": [{"action": "write", "path": "相对路径", "content": "写入的内容", "mode": "replace", "old_text": null, "offset": null}]}
+action 为 read/write/create/scan，mode 为 replace/insert/append，offset 是 insert 模式下的字节偏移，不需要的字段为 null。
+不需要操作时 operations 为空数组。`
+
+// nullable 返回允许为 null 的字段定义。strict 模式要求所有字段必填，可选字段以 null 表示
+func nullable(typ, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        []string{typ, "null"},
+		"description": description,
+	}
+}
+
+// ReplyFormat 返回 Reply 的 JSON Schema，字段与 FileOperation 一致
+func ReplyFormat() types.ResponseFormat {
+	mode := nullable("string", "写入模式，默认 replace")
+	mode["enum"] = []interface{}{"replace", "insert", "append", nil}
+	
+	operation := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"read", "write", "create", "scan"},
+			},
+			"path":     pathParam,
+			"content":  nullable("string", "write/create 写入的内容"),
+			"mode":     mode,
+			"old_text": nullable("string", "被替换的原文"),
+			"offset":   nullable("integer", "insert 模式下的插入位置（字节偏移）"),
+		},
+		"required":             []string{"action", "path", "content", "mode", "old_text", "offset"},
+		"additionalProperties": false,
+	}
+	
+	return types.ResponseFormat{
+		Name: "file_operations",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"message": map[string]interface{}{
+					"type":        "string",
+					"description": "给用户的说明",
+				},
+				"operations": map[string]interface{}{
+					"type":  "array",
+					"items": operation,
+				},
+			},
+			"required":             []string{"message", "operations"},
+			"additionalProperties": false,
+		},
+	}
+}
+
+// ParseReply 解析结构化输出的回复
+func ParseReply(content string) (Reply, error) {
+	var reply Reply
+	if err := json.Unmarshal([]byte(content), &reply); err != nil {
+		return reply, fmt.Errorf("回复不是有效的 JSON: %v", err)
+	}
+	return reply, nil
+}
+
+// fencePattern 匹配 Markdown 代码围栏中的内容
+var fencePattern = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*\\n(.*?)```")
+
+// ExtractReply 从自由文本回复中提取操作指令，用于不支持结构化输出的供应商。
+// 依次尝试整段回复、代码围栏中的内容、以及从首个以 [ 或 { 开头的行起的内容，
+// 接受操作数组、Reply 对象或单个操作，只有 Reply 对象带有 Message。回复中没有 JSON 时返回空的 Reply
+func ExtractReply(content string) (Reply, error) {
+	candidates := []string{content}
+	for _, m := range fencePattern.FindAllStringSubmatch(content, -1) {
+		candidates = append(candidates, m[1])
+	}
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+			candidates = append(candidates, strings.Join(lines[i:], "\n"))
+			break
+		}
+	}
+	
+	var firstErr error
+	found := false
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if !strings.HasPrefix(candidate, "[") && !strings.HasPrefix(candidate, "{") {
+			continue
+		}
+		found = true
+		reply, err := decodeReply(candidate)
+		if err == nil {
+			return reply, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if !found {
+		return Reply{}, nil
+	}
+	return Reply{}, firstErr
+}
+
+// decodeReply 解析文本开头的一个 JSON 值，其后的文字忽略
+func decodeReply(text string) (Reply, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(strings.NewReader(text)).Decode(&raw); err != nil {
+		return Reply{}, err
+	}
+	
+	if raw[0] == '[' {
+		var reply Reply
+		err := json.Unmarshal(raw, &reply.Operations)
+		return reply, err
+	}
+	
+	var object struct {
+		Message    *string                `json:"message"`
+		Operations *[]types.FileOperation `json:"operations"`
+		types.FileOperation
+	}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return Reply{}, err
+	}
+	switch {
+	case object.Message != nil || object.Operations != nil:
+		reply := Reply{}
+		if object.Message != nil {
+			reply.Message = *object.Message
+		}
+		if object.Operations != nil {
+			reply.Operations = *object.Operations
+		}
+		return reply, nil
+	case object.Action != "":
+		return Reply{Operations: []types.FileOperation{object.FileOperation}}, nil
+	default:
+		return Reply{}, fmt.Errorf("JSON 中没有操作指令")
+	}
+}
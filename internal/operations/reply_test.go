@@ -0,0 +1,44 @@
+package operations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+func TestExtractReply(t *testing.T) {
+	write := types.FileOperation{Action: "write", Path: "a.txt", Content: "x"}
+	tests := []struct {
+		name    string
+		content string
+		want    Reply
+		wantErr string
+	}{
+		{"plain text", "没有操作", Reply{}, ""},
+		{"reply object keeps message", `{"message": "已修改", "operations": [{"action": "write", "path": "a.txt", "content": "x"}]}`,
+			Reply{Message: "已修改", Operations: []types.FileOperation{write}}, ""},
+		{"message only", `{"message": "无需修改", "operations": []}`, Reply{Message: "无需修改", Operations: []types.FileOperation{}}, ""},
+		{"operation array", "说明\n```json\n[{\"action\": \"write\", \"path\": \"a.txt\", \"content\": \"x\"}]\n```", Reply{Operations: []types.FileOperation{write}}, ""},
+		{"single operation", "说明\n{\"action\": \"write\", \"path\": \"a.txt\", \"content\": \"x\"}\n结束", Reply{Operations: []types.FileOperation{write}}, ""},
+		{"json without operations", `{"foo": 1}`, Reply{}, "没有操作指令"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractReply(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExtractReply error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractReply = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
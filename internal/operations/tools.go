@@ -0,0 +1,108 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// pathParam 是各工具共用的 path 参数定义
+var pathParam = map[string]interface{}{
+	"type":        "string",
+	"description": "相对于项目根目录的路径",
+}
+
+// ToolDefinitions 返回 read/write/create/scan 四种操作对应的工具定义
+func ToolDefinitions() []types.Tool {
+	return []types.Tool{
+		{
+			Name:        "read",
+			Description: "读取项目中的文件内容",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": pathParam,
+				},
+				"required": []string{"path"},
+			},
+		},
+		{
+			Name:        "write",
+			Description: "修改已存在的文件，需用户确认",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": pathParam,
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "要写入的内容",
+					},
+					"mode": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"replace", "insert", "append"},
+						"description": "写入模式，默认 replace",
+					},
+					"old_text": map[string]interface{}{
+						"type":        "string",
+						"description": "被替换的原文（可选）",
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "insert 模式下的插入位置（字节偏移）",
+					},
+				},
+				"required": []string{"path", "content"},
+			},
+		},
+		{
+			Name:        "create",
+			Description: "创建新文件，需用户确认",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": pathParam,
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "文件内容",
+					},
+				},
+				"required": []string{"path", "content"},
+			},
+		},
+		{
+			Name:        "scan",
+			Description: "扫描额外的目录并加入项目结构，需用户确认",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": pathParam,
+				},
+				"required": []string{"path"},
+			},
+		},
+	}
+}
+
+// OperationFromToolCall 将模型返回的工具调用转换为文件操作
+func OperationFromToolCall(call types.ToolCall) (types.FileOperation, error) {
+	var op types.FileOperation
+	
+	switch call.Name {
+	case "read", "write", "create", "scan":
+	default:
+		return op, fmt.Errorf("未知的工具: %s", call.Name)
+	}
+	
+	if call.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Arguments), &op); err != nil {
+			return op, fmt.Errorf("工具 %s 的参数无效: %v", call.Name, err)
+		}
+	}
+	
+	op.Action = call.Name
+	if op.Path == "" {
+		return op, fmt.Errorf("工具 %s 缺少 path 参数", call.Name)
+	}
+	return op, nil
+}
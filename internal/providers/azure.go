@@ -0,0 +1,364 @@
+package providers
+
+import (
+	"context"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+const defaultAzureAPIVersion = "2024-06-01"
+
+// azureJSONSchemaVersion 是最早支持 json_schema 的 API 版本，更早的版本只支持 JSON 模式
+const azureJSONSchemaVersion = "2024-08-01"
+
+// azureStreamUsageVersion 是最早支持 stream_options 的 API 版本，更早的版本流式响应不附带用量
+const azureStreamUsageVersion = "2024-09-01"
+
+func init() {
+	Register(Registration{
+		Name:        "azure",
+		Description: "Azure OpenAI (按部署调用)",
+		Validate:    validateAzureConfig,
+		Fields:      []string{"api_base", "deployment", "version", "auth_type", "api_key", "auth_key", "max_tokens"},
+		New: func(config types.APIConfig) (types.AIProvider, error) {
+			return NewAzureProvider(config)
+		},
+	})
+}
+
+type AzureProvider struct {
+	config types.APIConfig
+	client *http.Client
+}
+
+// validateAzureConfig 检查 azure 配置的必填字段
+func validateAzureConfig(config types.APIConfig) error {
+	if config.APIBase == "" || config.Deployment == "" {
+		return fmt.Errorf("Azure OpenAI requires APIBase and Deployment")
+	}
+	
+	switch config.AuthType {
+	case "", "api-key":
+		if config.APIKey == "" && config.AuthKey == "" {
+			return fmt.Errorf("Azure OpenAI api-key auth requires APIKey")
+		}
+	case "aad", "bearer":
+		if config.AuthKey == "" {
+			return fmt.Errorf("Azure OpenAI AAD auth requires AuthKey (access token)")
+		}
+	default:
+		return fmt.Errorf("unsupported Azure auth type: %s", config.AuthType)
+	}
+	return nil
+}
+
+func NewAzureProvider(config types.APIConfig) (*AzureProvider, error) {
+	if err := validateAzureConfig(config); err != nil {
+		return nil, err
+	}
+	
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &AzureProvider{
+		config: config,
+		client: client,
+	}, nil
+}
+
+// apiVersion 返回配置的 API 版本，未配置时使用默认版本
+func (p *AzureProvider) apiVersion() string {
+	if p.config.Version == "" {
+		return defaultAzureAPIVersion
+	}
+	return p.config.Version
+}
+
+// endpoint 构造 /openai/deployments/{deployment}/chat/completions?api-version= 地址
+func (p *AzureProvider) endpoint() string {
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		strings.TrimRight(p.config.APIBase, "/"),
+		url.PathEscape(p.config.Deployment),
+		url.QueryEscape(p.apiVersion()),
+	)
+}
+
+// setAuthHeader 根据 AuthType 选择 api-key 头或 AAD Bearer 令牌
+func (p *AzureProvider) setAuthHeader(req *http.Request) {
+	switch p.config.AuthType {
+	case "aad", "bearer":
+		req.Header.Set("Authorization", "Bearer "+p.config.AuthKey)
+	default:
+		key := p.config.APIKey
+		if key == "" {
+			key = p.config.AuthKey
+		}
+		req.Header.Set("api-key", key)
+	}
+}
+
+// newRequest 构造部署的 chat completions 请求
+func (p *AzureProvider) newRequest(ctx context.Context, chat chatRequest) (*http.Request, error) {
+	if err := checkImages(p, chat.Messages); err != nil {
+		return nil, err
+	}
+	
+	chat.Params = generationParams(ctx, p.config.GenerationParams)
+	chat.FormatMode = p.formatMode()
+	
+	requestBody := map[string]interface{}{}
+	
+	if p.config.MaxTokens > 0 {
+		requestBody["max_tokens"] = p.config.MaxTokens
+	}
+	
+	chat.apply(requestBody)
+	
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	
+	req.Header.Set("Content-Type", "application/json")
+	p.setAuthHeader(req)
+	return req, nil
+}
+
+// responseError 将非 200 响应转为错误，内容过滤错误给出可读说明
+func (p *AzureProvider) responseError(resp *http.Response) error {
+	apiErr := newAPIError("Azure OpenAI", resp)
+	if msg := azureContentFilterMessage([]byte(apiErr.Body)); msg != "" {
+		return fmt.Errorf("Azure OpenAI content filter: %s", msg)
+	}
+	return apiErr
+}
+
+func (p *AzureProvider) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	return p.send(ctx, chatRequest{Messages: messages})
+}
+
+// send 发送一次性请求
+func (p *AzureProvider) send(ctx context.Context, chat chatRequest) (types.Response, error) {
+	req, err := p.newRequest(ctx, chat)
+	if err != nil {
+		return types.Response{}, err
+	}
+	
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return types.Response{}, p.responseError(resp)
+	}
+	
+	var response struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason         string                        `json:"finish_reason"`
+			ContentFilterResults map[string]azureFilterVerdict `json:"content_filter_results"`
+		} `json:"choices"`
+		Usage chatUsage `json:"usage"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return types.Response{}, err
+	}
+	
+	if len(response.Choices) == 0 {
+		return types.Response{}, fmt.Errorf("no completions received from Azure OpenAI")
+	}
+	
+	choice := response.Choices[0]
+	if choice.FinishReason == "content_filter" {
+		msg := describeFilterResults(choice.ContentFilterResults)
+		if msg == "" {
+			msg = "completion was filtered"
+		}
+		return types.Response{}, fmt.Errorf("Azure OpenAI content filter: %s", msg)
+	}
+	
+	return withModel(types.Response{
+		Content:      choice.Message.Content,
+		FinishReason: choice.FinishReason,
+		Model:        response.Model,
+		Usage:        response.Usage.usage(),
+	}, p.GetModel()), nil
+}
+
+func (p *AzureProvider) StreamRequest(ctx context.Context, messages []types.Message, onChunk func(string)) (types.Response, error) {
+	return p.stream(ctx, chatRequest{Messages: messages, Stream: true}, onChunk)
+}
+
+// stream 发送流式请求，每收到一段文本调用一次 onChunk
+func (p *AzureProvider) stream(ctx context.Context, chat chatRequest, onChunk func(string)) (types.Response, error) {
+	chat.StreamUsage = p.apiVersion() >= azureStreamUsageVersion
+	req, err := p.newRequest(ctx, chat)
+	if err != nil {
+		return types.Response{}, err
+	}
+	
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return types.Response{}, p.responseError(resp)
+	}
+	
+	response, err := readChatStream(resp.Body, onChunk, reasoningHandler(ctx))
+	if err != nil {
+		return types.Response{}, err
+	}
+	if response.FinishReason == "content_filter" {
+		return types.Response{}, fmt.Errorf("Azure OpenAI content filter: completion was filtered")
+	}
+	
+	// 旧 API 版本不支持 stream_options，用量按字符估算
+	return withModel(withEstimatedUsage(response, chat.Messages), p.GetModel()), nil
+}
+
+func (p *AzureProvider) SendStructured(ctx context.Context, messages []types.Message, format types.ResponseFormat, onChunk func(string)) (types.Response, error) {
+	if onChunk == nil {
+		return p.send(ctx, chatRequest{Messages: messages, Format: &format})
+	}
+	return p.stream(ctx, chatRequest{Messages: messages, Stream: true, Format: &format}, onChunk)
+}
+
+func (p *AzureProvider) StreamWithTools(ctx context.Context, messages []types.Message, tools []types.Tool, onChunk func(string)) (types.Response, error) {
+	return p.stream(ctx, chatRequest{Messages: messages, Tools: tools, Stream: true}, onChunk)
+}
+
+func (p *AzureProvider) SendWithTools(ctx context.Context, messages []types.Message, tools []types.Tool) (types.Response, error) {
+	req, err := p.newRequest(ctx, chatRequest{Messages: messages, Tools: tools})
+	if err != nil {
+		return types.Response{}, err
+	}
+	
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return types.Response{}, p.responseError(resp)
+	}
+	
+	response, err := decodeChatResponse(resp.Body, "Azure OpenAI")
+	if err != nil {
+		return types.Response{}, err
+	}
+	return withModel(response, p.GetModel()), nil
+}
+
+// azureFilterVerdict 是内容过滤结果中单个类别的判定
+type azureFilterVerdict struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity,omitempty"`
+	Detected bool   `json:"detected,omitempty"`
+}
+
+// azureContentFilterMessage 将 Azure 的 content_filter 错误体转为可读说明，
+// 不是内容过滤错误时返回空字符串
+func azureContentFilterMessage(body []byte) string {
+	var payload struct {
+		Error struct {
+			Code       string `json:"code"`
+			Message    string `json:"message"`
+			InnerError struct {
+				Code                string                        `json:"code"`
+				ContentFilterResult map[string]azureFilterVerdict `json:"content_filter_result"`
+			} `json:"innererror"`
+		} `json:"error"`
+	}
+	
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	
+	if payload.Error.Code != "content_filter" && payload.Error.InnerError.Code != "ResponsibleAIPolicyViolation" {
+		return ""
+	}
+	
+	if msg := describeFilterResults(payload.Error.InnerError.ContentFilterResult); msg != "" {
+		return "prompt was blocked (" + msg + ")"
+	}
+	return "prompt was blocked by Azure's content management policy"
+}
+
+// describeFilterResults 列出被拦截的类别及其严重程度，例如 "hate: medium, violence: high"
+func describeFilterResults(results map[string]azureFilterVerdict) string {
+	var parts []string
+	for category, verdict := range results {
+		if !verdict.Filtered {
+			continue
+		}
+		if verdict.Severity != "" {
+			parts = append(parts, fmt.Sprintf("%s: %s", category, verdict.Severity))
+		} else {
+			parts = append(parts, category)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func (p *AzureProvider) GetName() string {
+	return "Azure OpenAI"
+}
+
+func (p *AzureProvider) GetModel() string {
+	if p.config.Model != "" {
+		return p.config.Model
+	}
+	return p.config.Deployment
+}
+
+func (p *AzureProvider) SupportsFeature(feature types.Capability) bool {
+	switch feature {
+	case types.CapLongContext, types.CapEnterprise, types.CapTools:
+		return true
+	case types.CapMultimodal:
+		// 部署名不一定是模型名，按配置的 model 判断
+		info, _ := LookupModel(p.config.Model)
+		return info.Supports(types.CapMultimodal)
+	case types.CapStructuredOutput:
+		return p.formatMode() != formatNone
+	default:
+		return false
+	}
+}
+
+// formatMode 按配置的 model 与 API 版本判断，未配置 model 时只使用 JSON 模式
+func (p *AzureProvider) formatMode() formatMode {
+	if p.config.Model == "" {
+		return formatJSONObject
+	}
+	mode := openAIFormatMode(p.config.Model)
+	if mode == formatJSONSchema && p.apiVersion() < azureJSONSchemaVersion {
+		return formatJSONObject
+	}
+	return mode
+}
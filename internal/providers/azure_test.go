@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+func TestAzureStreamUsage(t *testing.T) {
+	tests := []struct {
+		version   string
+		wantUsage bool // 请求是否附带 stream_options
+	}{
+		{"", false},
+		{"2024-06-01", false},
+		{"2024-09-01-preview", true},
+		{"2024-10-21", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			var gotUsage bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					StreamOptions *struct {
+						IncludeUsage bool `json:"include_usage"`
+					} `json:"stream_options"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				gotUsage = body.StreamOptions != nil && body.StreamOptions.IncludeUsage
+				
+				w.Header().Set("Content-Type", "text/event-stream")
+				io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hello world\"},\"finish_reason\":\"stop\"}]}\n\n")
+				if gotUsage {
+					io.WriteString(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":2,\"total_tokens\":9}}\n\n")
+				}
+				io.WriteString(w, "data: [DONE]\n\n")
+			}))
+			defer srv.Close()
+			
+			p, err := NewAzureProvider(types.APIConfig{APIBase: srv.URL, Deployment: "gpt-4o", Version: tt.version, APIKey: stubAPIKey, MaxRetries: -1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := p.StreamRequest(context.Background(), userMessage("please say hello world"), func(string) {})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotUsage != tt.wantUsage {
+				t.Errorf("stream_options.include_usage = %v, want %v", gotUsage, tt.wantUsage)
+			}
+			// 没有报告用量时显式回退到估算值，而不是零值
+			usage := response.Usage
+			if usage.Estimated == tt.wantUsage || usage.PromptTokens == 0 || usage.CompletionTokens == 0 {
+				t.Errorf("usage = %+v, want estimated = %v", usage, !tt.wantUsage)
+			}
+		})
+	}
+}
@@ -1,103 +1,374 @@
 package providers
 
 import (
-	"crypto/hmac"
-	"crypto/sha1"
-	"encoding/base64"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 	
 	"github.com/yantianyv/AkashaTerminal/pkg/types"
 )
 
+func init() {
+	Register(Registration{
+		Name:        "bailian",
+		Description: "阿里百炼 (DashScope 应用)",
+		Validate:    validateBailianConfig,
+		Fields:      []string{"app_id", "auth_type", "api_key", "access_key_id", "access_key_secret", "agent_id", "api_base", "model"},
+		New: func(config types.APIConfig) (types.AIProvider, error) {
+			return NewBailianProvider(config)
+		},
+	})
+}
+
+// 百炼的两种认证方式
+const (
+	bailianAuthAPIKey = "api_key" // DashScope API Key，默认
+	bailianAuthAKSK   = "aksk"    // 阿里云 AccessKey 签名换取临时 token
+)
+
+var (
+	// bailianDashScopeBase 是 DashScope 应用调用接口的默认地址，api_base 可覆盖
+	bailianDashScopeBase = "https://dashscope.aliyuncs.com"
+	
+	// aksk 认证时签名请求 CreateToken 的 OpenAPI 地址，以及使用 token 调用的应用接口
+	bailianOpenAPIEndpoint     = "https://bailian.cn-beijing.aliyuncs.com"
+	bailianLegacyCompletionURL = "https://bailian.aliyuncs.com/v2/app/completions"
+)
+
+const (
+	bailianOpenAPIVersion = "2023-06-01"
+	
+	// bailianTokenRefresh 在 token 过期前提前刷新的时间
+	bailianTokenRefresh = time.Minute
+)
+
 type BailianProvider struct {
 	config types.APIConfig
+	client *http.Client
+	
+	mu          sync.Mutex
+	sessionID   string    // 服务端保存对话历史的会话，后续轮次复用
+	token       string    // aksk 认证换取的临时 token
+	tokenExpiry time.Time
+}
+
+// validateBailianConfig 检查 bailian 配置的必填字段
+func validateBailianConfig(config types.APIConfig) error {
+	if config.AppID == "" {
+		return fmt.Errorf("Bailian requires AppID")
+	}
+	
+	switch config.AuthType {
+	case "", bailianAuthAPIKey:
+		if config.APIKey == "" {
+			return fmt.Errorf("Bailian requires a DashScope API key")
+		}
+	case bailianAuthAKSK:
+		if config.AccessKeyID == "" || config.AccessKeySecret == "" || config.AgentID == "" {
+			return fmt.Errorf("Bailian aksk auth requires AccessKeyID, AccessKeySecret and AgentID (agent key)")
+		}
+	default:
+		return fmt.Errorf("unsupported Bailian auth_type: %s", config.AuthType)
+	}
+	return nil
 }
 
-func NewProvider(config types.APIConfig) (*BailianProvider, error) {
-	if config.AppID == "" || config.AccessKeyID == "" || config.AccessKeySecret == "" {
-		return nil, fmt.Errorf("Bailian requires AppID, AccessKeyID and AccessKeySecret")
+func NewBailianProvider(config types.APIConfig) (*BailianProvider, error) {
+	if err := validateBailianConfig(config); err != nil {
+		return nil, err
+	}
+	
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
 	}
 	
 	return &BailianProvider{
 		config: config,
+		client: client,
 	}, nil
 }
 
-func (p *BailianProvider) generateSignature() (string, int64) {
-	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
-	strToSign := fmt.Sprintf("%d", timestamp)
+func (p *BailianProvider) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	if err := checkImages(p, messages); err != nil {
+		return types.Response{}, err
+	}
+	
+	p.mu.Lock()
+	sessionID := p.sessionID
+	p.mu.Unlock()
+	
+	prompt := bailianPrompt(messages, sessionID != "")
+	
+	var response types.Response
+	var err error
+	if p.config.AuthType == bailianAuthAKSK {
+		response, sessionID, err = p.sendWithToken(ctx, prompt, sessionID)
+	} else {
+		response, sessionID, err = p.sendDashScope(ctx, prompt, sessionID)
+	}
+	if err != nil {
+		return types.Response{}, err
+	}
 	
-	h := hmac.New(sha1.New, []byte(p.config.AccessKeySecret))
-	h.Write([]byte(strToSign))
-	signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	p.mu.Lock()
+	p.sessionID = sessionID
+	p.mu.Unlock()
 	
-	return signature, timestamp
+	return withModel(response, p.GetModel()), nil
+}
+
+// bailianPrompt 生成本轮的 prompt。首轮发送完整对话；
+// 之后历史由服务端按 session_id 保存，只发送系统消息与最新的用户消息
+func bailianPrompt(messages []types.Message, resumed bool) string {
+	if !resumed {
+		return flattenMessages(messages)
+	}
+	
+	var current []types.Message
+	for _, msg := range messages {
+		if msg.Role == types.RoleSystem {
+			current = append(current, msg)
+		}
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == types.RoleUser {
+			current = append(current, messages[i])
+			break
+		}
+	}
+	return flattenMessages(current)
 }
 
-func (p *BailianProvider) SendRequest(prompt string, state interface{}) (string, error) {
-	url := "https://bailian.aliyuncs.com/v2/app/completions"
+// sendDashScope 使用 API Key 调用 DashScope 应用接口
+func (p *BailianProvider) sendDashScope(ctx context.Context, prompt, sessionID string) (types.Response, string, error) {
+	base := bailianDashScopeBase
 	if p.config.APIBase != "" {
-		url = p.config.APIBase
+		base = p.config.APIBase
 	}
+	endpoint := strings.TrimSuffix(base, "/") + "/api/v1/apps/" + url.PathEscape(p.config.AppID) + "/completion"
 	
-	signature, timestamp := p.generateSignature()
+	input := map[string]interface{}{"prompt": prompt}
+	if sessionID != "" {
+		input["session_id"] = sessionID
+	}
 	
-	requestBody := map[string]interface{}{
-		"appId":    p.config.AppID,
-		"prompt":   prompt,
-		"sessionId": time.Now().Unix(),
+	// 应用接口只接受部分采样参数
+	params := generationParams(ctx, p.config.GenerationParams)
+	parameters := map[string]interface{}{}
+	applyGenerationParams(parameters, types.GenerationParams{
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		Seed:        params.Seed,
+	})
+	
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"input":      input,
+		"parameters": parameters,
+	})
+	if err != nil {
+		return types.Response{}, "", err
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return types.Response{}, "", err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
 	
-	if p.config.AgentID != "" {
-		requestBody["agentId"] = p.config.AgentID
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, "", err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return types.Response{}, "", newAPIError("Bailian", resp)
+	}
+	
+	var response struct {
+		Output struct {
+			Text         string `json:"text"`
+			FinishReason string `json:"finish_reason"`
+			SessionID    string `json:"session_id"`
+		} `json:"output"`
+		Usage struct {
+			Models []struct {
+				ModelID      string `json:"model_id"`
+				InputTokens  int    `json:"input_tokens"`
+				OutputTokens int    `json:"output_tokens"`
+			} `json:"models"`
+		} `json:"usage"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return types.Response{}, "", err
 	}
 	
+	result := types.Response{
+		Content:      response.Output.Text,
+		FinishReason: response.Output.FinishReason,
+	}
+	// 应用可能串联多个模型，用量累加，模型取第一个
+	for _, m := range response.Usage.Models {
+		if result.Model == "" {
+			result.Model = m.ModelID
+		}
+		result.Usage.PromptTokens += m.InputTokens
+		result.Usage.CompletionTokens += m.OutputTokens
+	}
+	result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	
+	if response.Output.SessionID != "" {
+		sessionID = response.Output.SessionID
+	}
+	return result, sessionID, nil
+}
+
+// sendWithToken 使用 AccessKey 签名换取的 token 调用百炼应用接口
+func (p *BailianProvider) sendWithToken(ctx context.Context, prompt, sessionID string) (types.Response, string, error) {
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return types.Response{}, "", err
+	}
+	
+	endpoint := bailianLegacyCompletionURL
+	if p.config.APIBase != "" {
+		endpoint = p.config.APIBase
+	}
+	
+	requestBody := map[string]interface{}{
+		"RequestId": newIdempotencyKey(),
+		"AppId":     p.config.AppID,
+		"Prompt":    prompt,
+	}
+	if sessionID != "" {
+		requestBody["SessionId"] = sessionID
+	}
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", err
+		return types.Response{}, "", err
 	}
 	
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return types.Response{}, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, "", err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return types.Response{}, "", newAPIError("Bailian", resp)
+	}
+	
+	var response struct {
+		Success bool   `json:"Success"`
+		Code    string `json:"Code"`
+		Message string `json:"Message"`
+		Data    struct {
+			Text      string `json:"Text"`
+			SessionID string `json:"SessionId"`
+		} `json:"Data"`
+	}
+	
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return types.Response{}, "", err
+	}
+	
+	if !response.Success {
+		return types.Response{}, "", fmt.Errorf("Bailian API error: %s - %s", response.Code, response.Message)
+	}
+	
+	if response.Data.SessionID != "" {
+		sessionID = response.Data.SessionID
+	}
+	return types.Response{Content: response.Data.Text}, sessionID, nil
+}
+
+// accessToken 返回缓存的临时 token，过期前通过签名的 CreateToken 请求刷新
+func (p *BailianProvider) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	if p.token != "" && time.Until(p.tokenExpiry) > bailianTokenRefresh {
+		return p.token, nil
+	}
+	
+	endpoint, err := url.Parse(bailianOpenAPIEndpoint)
 	if err != nil {
 		return "", err
 	}
+	endpoint.Path = "/"
+	endpoint.RawQuery = url.Values{"AgentKey": {p.config.AgentID}}.Encode()
 	
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Bailian-AppId", p.config.AppID)
-	req.Header.Set("X-Bailian-Token", signature)
-	req.Header.Set("X-Bailian-Timestamp", fmt.Sprintf("%d", timestamp))
+	signed := &acs3Request{
+		Method: "POST",
+		URL:    endpoint,
+		Headers: map[string]string{
+			"host":                  endpoint.Host,
+			"x-acs-action":          "CreateToken",
+			"x-acs-version":         bailianOpenAPIVersion,
+			"x-acs-date":            acs3Date(time.Now()),
+			"x-acs-signature-nonce": newIdempotencyKey(),
+		},
+	}
+	authorization := signACS3(signed, p.config.AccessKeyID, p.config.AccessKeySecret)
 	
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	for name, value := range signed.Headers {
+		if name != "host" {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Header.Set("Authorization", authorization)
+	
+	resp, err := p.client.Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Bailian API error: %s - %s", resp.Status, string(body))
+		return "", newAPIError("Bailian OpenAPI", resp)
 	}
 	
 	var response struct {
-		Success bool `json:"success"`
+		Success bool   `json:"Success"`
+		Code    string `json:"Code"`
+		Message string `json:"Message"`
 		Data    struct {
-			Text string `json:"text"`
-		} `json:"data"`
+			Token       string `json:"Token"`
+			ExpiredTime int64  `json:"ExpiredTime"` // Unix 时间（秒）
+		} `json:"Data"`
 	}
 	
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return "", err
 	}
 	
-	if !response.Success {
-		return "", fmt.Errorf("Bailian API returned non-success response")
+	if !response.Success || response.Data.Token == "" {
+		return "", fmt.Errorf("Bailian CreateToken failed: %s - %s", response.Code, response.Message)
 	}
 	
-	return response.Data.Text, nil
+	p.token = response.Data.Token
+	p.tokenExpiry = time.Unix(response.Data.ExpiredTime, 0)
+	return p.token, nil
 }
 
 func (p *BailianProvider) GetName() string {
@@ -111,11 +382,11 @@ func (p *BailianProvider) GetModel() string {
 	return "bailian-plus"
 }
 
-func (p *BailianProvider) SupportsFeature(feature string) bool {
+func (p *BailianProvider) SupportsFeature(feature types.Capability) bool {
 	switch feature {
-	case "enterprise":
+	case types.CapEnterprise:
 		return true
-	case "custom_model":
+	case types.CapCustomModel:
 		return p.config.Model != "bailian-plus"
 	default:
 		return false
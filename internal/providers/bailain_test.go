@@ -0,0 +1,241 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+const (
+	stubAccessKeyID     = "stub-access-key-id"
+	stubAccessKeySecret = "stub-access-key-secret"
+)
+
+// bailianStub 模拟 CreateToken、token 调用的应用接口与 DashScope 应用接口
+type bailianStub struct {
+	t *testing.T
+	
+	mu           sync.Mutex
+	tokenTTL     time.Duration // 新 token 的有效期
+	tokenStatus  int           // CreateToken 返回的状态码，0 表示 200
+	tokenCalls   int
+	tokens       []string // 已签发的 token
+	sessionIDs   []string // DashScope 请求中收到的 session_id，按请求顺序
+	prompts      []string
+	completionOK int
+}
+
+func newBailianStub(t *testing.T) *bailianStub {
+	s := &bailianStub{t: t, tokenTTL: time.Hour}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	
+	// 替换各接口地址，测试结束后恢复
+	openAPI, legacy, dashScope := bailianOpenAPIEndpoint, bailianLegacyCompletionURL, bailianDashScopeBase
+	bailianOpenAPIEndpoint = srv.URL
+	bailianLegacyCompletionURL = srv.URL + "/v2/app/completions"
+	bailianDashScopeBase = srv.URL
+	t.Cleanup(func() {
+		bailianOpenAPIEndpoint, bailianLegacyCompletionURL, bailianDashScopeBase = openAPI, legacy, dashScope
+	})
+	return s
+}
+
+func (s *bailianStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	switch {
+	case r.Header.Get("x-acs-action") == "CreateToken":
+		s.tokenCalls++
+		if err := verifyACS3(r); err != nil {
+			s.t.Errorf("CreateToken: %v", err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if s.tokenStatus != 0 {
+			http.Error(w, "unavailable", s.tokenStatus)
+			return
+		}
+		token := fmt.Sprintf("stub-token-%d", s.tokenCalls)
+		s.tokens = append(s.tokens, token)
+		fmt.Fprintf(w, `{"Success":true,"Data":{"Token":%q,"ExpiredTime":%d}}`, token, time.Now().Add(s.tokenTTL).Unix())
+	
+	case r.URL.Path == "/v2/app/completions":
+		want := "Bearer " + s.tokens[len(s.tokens)-1]
+		if got := r.Header.Get("Authorization"); got != want {
+			s.t.Errorf("completion Authorization = %q, want %q", got, want)
+		}
+		s.completionOK++
+		fmt.Fprintf(w, `{"Success":true,"Data":{"Text":"reply %d","SessionId":"legacy-session"}}`, s.completionOK)
+	
+	case strings.HasPrefix(r.URL.Path, "/api/v1/apps/"):
+		var body struct {
+			Input struct {
+				Prompt    string `json:"prompt"`
+				SessionID string `json:"session_id"`
+			} `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.sessionIDs = append(s.sessionIDs, body.Input.SessionID)
+		s.prompts = append(s.prompts, body.Input.Prompt)
+		fmt.Fprintf(w, `{"output":{"text":"ok","finish_reason":"stop","session_id":"session-%d"},`+
+			`"usage":{"models":[{"model_id":"qwen-plus","input_tokens":3,"output_tokens":2}]}}`, len(s.sessionIDs))
+	
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// verifyACS3 按收到的请求重新计算签名并与 Authorization 比较
+func verifyACS3(r *http.Request) error {
+	u := *r.URL
+	u.Scheme, u.Host = "http", r.Host
+	headers := map[string]string{"host": r.Host}
+	for name := range r.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-acs-") {
+			headers[lower] = r.Header.Get(name)
+		}
+	}
+	want := signACS3(&acs3Request{Method: r.Method, URL: &u, Headers: headers}, stubAccessKeyID, stubAccessKeySecret)
+	if got := r.Header.Get("Authorization"); got != want {
+		return fmt.Errorf("signature mismatch:\n got %s\nwant %s", got, want)
+	}
+	return nil
+}
+
+func newAKSKProvider(t *testing.T) *BailianProvider {
+	t.Helper()
+	p, err := NewBailianProvider(types.APIConfig{
+		Provider:        "bailian",
+		AppID:           "app-1",
+		AuthType:        bailianAuthAKSK,
+		AccessKeyID:     stubAccessKeyID,
+		AccessKeySecret: stubAccessKeySecret,
+		AgentID:         "agent-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func userMessage(content string) []types.Message {
+	return []types.Message{{Role: types.RoleUser, Content: content}}
+}
+
+func TestBailianTokenRefresh(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		wantTokens int // 两次请求共签发的 token 数
+	}{
+		{"token reused while valid", time.Hour, 1},
+		{"token refreshed before expiry", bailianTokenRefresh / 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newBailianStub(t)
+			stub.tokenTTL = tt.ttl
+			p := newAKSKProvider(t)
+	
+			for i := 0; i < 2; i++ {
+				resp, err := p.SendRequest(context.Background(), userMessage("hi"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := fmt.Sprintf("reply %d", i+1); resp.Content != want {
+					t.Errorf("request %d content = %q, want %q", i+1, resp.Content, want)
+				}
+			}
+			if stub.tokenCalls != tt.wantTokens {
+				t.Errorf("CreateToken called %d times, want %d", stub.tokenCalls, tt.wantTokens)
+			}
+		})
+	}
+}
+
+// 签名中的 nonce 只能使用一次，CreateToken 失败时不能原样重发
+func TestBailianSignedRequestNotRetried(t *testing.T) {
+	stub := newBailianStub(t)
+	stub.tokenStatus = http.StatusServiceUnavailable
+	p := newAKSKProvider(t)
+	
+	if _, err := p.SendRequest(context.Background(), userMessage("hi")); err == nil {
+		t.Fatal("expected CreateToken error")
+	}
+	if stub.tokenCalls != 1 {
+		t.Errorf("CreateToken called %d times, want 1", stub.tokenCalls)
+	}
+}
+
+func TestBailianSessionReuse(t *testing.T) {
+	stub := newBailianStub(t)
+	p, err := NewBailianProvider(types.APIConfig{Provider: "bailian", AppID: "app-1", APIKey: "stub-dashscope-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	history := []types.Message{
+		{Role: types.RoleSystem, Content: "system prompt"},
+		{Role: types.RoleUser, Content: "first question"},
+	}
+	if _, err := p.SendRequest(context.Background(), history); err != nil {
+		t.Fatal(err)
+	}
+	history = append(history,
+		types.Message{Role: types.RoleAssistant, Content: "first answer"},
+		types.Message{Role: types.RoleUser, Content: "second question"},
+	)
+	resp, err := p.SendRequest(context.Background(), history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	if want := []string{"", "session-1"}; strings.Join(stub.sessionIDs, ",") != strings.Join(want, ",") {
+		t.Errorf("session_id sent = %q, want %q", stub.sessionIDs, want)
+	}
+	// 复用会话后只发送系统消息与最新的用户消息
+	if strings.Contains(stub.prompts[1], "first") || !strings.Contains(stub.prompts[1], "second question") {
+		t.Errorf("resumed prompt = %q", stub.prompts[1])
+	}
+	if resp.Usage.TotalTokens != 5 || resp.Model != "qwen-plus" {
+		t.Errorf("usage = %+v, model = %q", resp.Usage, resp.Model)
+	}
+}
+
+func TestBailianCassetteRedactsCredentials(t *testing.T) {
+	stub := newBailianStub(t)
+	path := filepath.Join(t.TempDir(), "bailian.json")
+	if err := UseCassette(path, CassetteRecord); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { UseCassette("", "") })
+	
+	p := newAKSKProvider(t)
+	if _, err := p.SendRequest(context.Background(), userMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range append([]string{stubAccessKeyID, stubAccessKeySecret, url.QueryEscape("agent-1")}, stub.tokens...) {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	
+	"github.com/yantianyv/AkashaTerminal/internal/fileutil"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+const (
+	defaultCacheTTL     = 24 * time.Hour
+	defaultCacheMaxSize = 100 << 20
+)
+
+// CacheEvent 描述一次请求是否命中了本地响应缓存
+type CacheEvent struct {
+	Hit bool
+}
+
+type cacheNotifierKey struct{}
+
+// WithCacheNotifier 返回携带缓存回调的上下文，启用缓存时每次请求查找缓存后会调用 fn
+func WithCacheNotifier(ctx context.Context, fn func(CacheEvent)) context.Context {
+	return context.WithValue(ctx, cacheNotifierKey{}, fn)
+}
+
+func notifyCache(ctx context.Context, event CacheEvent) {
+	if fn, ok := ctx.Value(cacheNotifierKey{}).(func(CacheEvent)); ok && fn != nil {
+		fn(event)
+	}
+}
+
+// responseCache 是保存在磁盘上的响应缓存，每条记录一个文件。
+// 记录的年龄按写入时的 created 计算，超过有效期的记录在读取或清理时删除；
+// 文件的修改时间表示最近使用时间，目录超过大小上限时据此淘汰
+type responseCache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+	
+	mu sync.Mutex
+}
+
+// cacheEntry 是缓存文件的格式
+type cacheEntry struct {
+	Created     time.Time `json:"created"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        string    `json:"body"`
+}
+
+var (
+	responseCacheMu sync.Mutex
+	activeResponses *responseCache
+)
+
+// UseResponseCache 设置之后创建的供应商使用的响应缓存，config.Enabled 为 false 时关闭。
+// 缓存位于用户缓存目录（Linux 上为 ~/.cache/akashaterminal/responses）
+func UseResponseCache(config types.CacheConfig) error {
+	responseCacheMu.Lock()
+	defer responseCacheMu.Unlock()
+	
+	if !config.Enabled {
+		activeResponses = nil
+		return nil
+	}
+	
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return err
+	}
+	c := &responseCache{
+		dir:     filepath.Join(dir, "akashaterminal", "responses"),
+		ttl:     defaultCacheTTL,
+		maxSize: defaultCacheMaxSize,
+	}
+	if config.TTLHours > 0 {
+		c.ttl = time.Duration(config.TTLHours) * time.Hour
+	}
+	if config.MaxSizeMB > 0 {
+		c.maxSize = int64(config.MaxSizeMB) << 20
+	}
+	activeResponses = c
+	return nil
+}
+
+func activeResponseCache() *responseCache {
+	responseCacheMu.Lock()
+	defer responseCacheMu.Unlock()
+	return activeResponses
+}
+
+// volatileFields 是每次请求都不同、与回复内容无关的请求体字段，不参与缓存键的计算，
+// 如百炼 aksk 接口的 RequestId
+var volatileFields = []string{"RequestId"}
+
+// cacheKey 由供应商、请求地址与请求体计算。请求体包含模型、生成参数与完整的对话，
+// 认证信息与 volatileFields 不参与计算
+func cacheKey(provider string, req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(provider + "\n" + req.Method + " " + req.URL.String() + "\n"))
+	h.Write(stripVolatileFields(body))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// stripVolatileFields 删除 JSON 对象顶层的 volatileFields，不含这些字段时原样返回
+func stripVolatileFields(body []byte) []byte {
+	var object map[string]json.RawMessage
+	if json.Unmarshal(body, &object) != nil {
+		return body
+	}
+	changed := false
+	for _, name := range volatileFields {
+		if _, ok := object[name]; ok {
+			delete(object, name)
+			changed = true
+		}
+	}
+	if !changed {
+		return body
+	}
+	stripped, err := json.Marshal(object)
+	if err != nil {
+		return body
+	}
+	return stripped
+}
+
+func (c *responseCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// get 返回未过期的记录，并更新其最近使用时间
+func (c *responseCache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	
+	var entry cacheEntry
+	if json.Unmarshal(data, &entry) != nil || time.Since(entry.Created) > c.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+	
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return &entry, true
+}
+
+// put 写入一条记录，随后淘汰超出大小上限的旧记录
+func (c *responseCache) put(key string, entry cacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := fileutil.WriteAtomic(path, data, 0600); err != nil {
+		return err
+	}
+	return c.prune()
+}
+
+// prune 删除过期记录，并按最近使用时间从旧到新删除，直到目录大小不超过上限
+func (c *responseCache) prune() error {
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	
+	var files []file
+	var total int64
+	err := filepath.WalkDir(c.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if created, ok := entryCreated(path); !ok || time.Since(created) > c.ttl {
+			os.Remove(path)
+			return nil
+		}
+		files = append(files, file{path, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		if total <= c.maxSize {
+			break
+		}
+		os.Remove(f.path)
+		total -= f.size
+	}
+	return nil
+}
+
+// entryCreated 只读取缓存文件开头的 created 字段，不解析整个响应体
+func entryCreated(path string) (time.Time, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	
+	decoder := json.NewDecoder(f)
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return time.Time{}, false
+	}
+	if tok, err := decoder.Token(); err != nil || tok != "created" {
+		return time.Time{}, false
+	}
+	var created time.Time
+	if decoder.Decode(&created) != nil {
+		return time.Time{}, false
+	}
+	return created, true
+}
+
+// cacheTransport 对 POST 请求按请求内容查找缓存，命中时不访问供应商；
+// 未命中时转发请求，完整读取的 200 响应（包括流式响应）写入缓存
+type cacheTransport struct {
+	base     http.RoundTripper
+	cache    *responseCache
+	provider string
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "POST" || req.GetBody == nil {
+		return t.base.RoundTrip(req)
+	}
+	
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey(t.provider, req, data)
+	
+	if entry, ok := t.cache.get(key); ok {
+		notifyCache(req.Context(), CacheEvent{Hit: true})
+		header := http.Header{}
+		if entry.ContentType != "" {
+			header.Set("Content-Type", entry.ContentType)
+		}
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Status:        "200 OK",
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(entry.Body)),
+			ContentLength: int64(len(entry.Body)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Request:       req,
+		}, nil
+	}
+	notifyCache(req.Context(), CacheEvent{Hit: false})
+	
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	
+	contentType := resp.Header.Get("Content-Type")
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		done: func(data []byte, complete bool) {
+			// 被取消或中断的响应不写入缓存
+			if !complete || len(bytes.TrimSpace(data)) == 0 {
+				return
+			}
+			t.cache.put(key, cacheEntry{Created: time.Now(), ContentType: contentType, Body: string(data)})
+		},
+	}
+	return resp, nil
+}
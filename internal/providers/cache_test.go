@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCacheKeyIgnoresVolatileFields(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://example.com/v2/app/completions", nil)
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"request id differs", `{"RequestId":"a","AppId":"x","Prompt":"hi"}`, `{"AppId":"x","Prompt":"hi","RequestId":"b"}`, true},
+		{"prompt differs", `{"RequestId":"a","Prompt":"hi"}`, `{"RequestId":"a","Prompt":"bye"}`, false},
+		{"nested field kept", `{"input":{"RequestId":"a"}}`, `{"input":{"RequestId":"b"}}`, false},
+		{"not json", `hi`, `hi`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			same := cacheKey("bailian", req, []byte(tt.a)) == cacheKey("bailian", req, []byte(tt.b))
+			if same != tt.same {
+				t.Errorf("same key = %v, want %v", same, tt.same)
+			}
+		})
+	}
+}
+
+// 读取会刷新修改时间，但记录的年龄始终按 created 计算
+func TestResponseCacheAgeUsesCreated(t *testing.T) {
+	c := &responseCache{dir: t.TempDir(), ttl: time.Hour, maxSize: 1 << 20}
+	fresh, stale := "aa01", "aa02"
+	if err := c.put(fresh, cacheEntry{Created: time.Now(), Body: "fresh"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.put(stale, cacheEntry{Created: time.Now().Add(-2 * time.Hour), Body: "stale"}); err != nil {
+		t.Fatal(err)
+	}
+	
+	// 过期记录的修改时间是新的，清理时仍应删除
+	if err := c.prune(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(c.path(stale)); !os.IsNotExist(err) {
+		t.Errorf("stale entry kept by prune: %v", err)
+	}
+	
+	// 修改时间很旧但 created 未过期的记录仍然有效
+	old := time.Now().Add(-24 * time.Hour)
+	os.Chtimes(c.path(fresh), old, old)
+	if err := c.prune(); err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := c.get(fresh); !ok || entry.Body != "fresh" {
+		t.Errorf("fresh entry = %+v, %v", entry, ok)
+	}
+}
@@ -0,0 +1,399 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	
+	"github.com/yantianyv/AkashaTerminal/internal/fileutil"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// 录制/回放模式，可通过环境变量或 UseCassette 选择
+const (
+	CassetteRecord = "record" // 正常请求并把请求/响应写入磁带文件
+	CassetteReplay = "replay" // 不访问网络，按顺序回放磁带中的响应
+	
+	cassetteEnvPath = "AKASHA_CASSETTE"      // 磁带文件路径
+	cassetteEnvMode = "AKASHA_CASSETTE_MODE" // record 或 replay，默认 replay
+	
+	redacted = "REDACTED"
+)
+
+// cassetteInteraction 是磁带中的一次请求与响应
+type cassetteInteraction struct {
+	Request struct {
+		Method  string              `json:"method"`
+		URL     string              `json:"url"`
+		Headers map[string][]string `json:"headers,omitempty"`
+		Body    string              `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		StatusCode int                 `json:"status_code"`
+		Status     string              `json:"status"`
+		Headers    map[string][]string `json:"headers,omitempty"`
+		Body       string              `json:"body"`
+	} `json:"response"`
+	
+	used bool // 回放时是否已被使用
+}
+
+// cassette 是一个磁带文件，同一路径的所有供应商共用一个实例
+type cassette struct {
+	path string
+	mode string
+	
+	mu           sync.Mutex
+	interactions []*cassetteInteraction
+}
+
+var (
+	cassetteMu     sync.Mutex
+	cassetteConfig struct{ path, mode string }
+	cassettes      = make(map[string]*cassette)
+)
+
+// UseCassette 设置之后创建的供应商使用的磁带文件与模式，path 为空时关闭。
+// 未调用时读取 AKASHA_CASSETTE 与 AKASHA_CASSETTE_MODE 环境变量
+func UseCassette(path, mode string) error {
+	if path != "" && mode != CassetteRecord && mode != CassetteReplay {
+		return fmt.Errorf("unknown cassette mode: %s (expected %s or %s)", mode, CassetteRecord, CassetteReplay)
+	}
+	
+	cassetteMu.Lock()
+	defer cassetteMu.Unlock()
+	
+	cassetteConfig.path, cassetteConfig.mode = path, mode
+	return nil
+}
+
+// activeCassette 返回当前生效的磁带，未启用时返回 nil
+func activeCassette() (*cassette, error) {
+	cassetteMu.Lock()
+	defer cassetteMu.Unlock()
+	
+	path, mode := cassetteConfig.path, cassetteConfig.mode
+	if path == "" {
+		path, mode = os.Getenv(cassetteEnvPath), os.Getenv(cassetteEnvMode)
+		if mode == "" {
+			mode = CassetteReplay
+		}
+	}
+	if path == "" {
+		return nil, nil
+	}
+	
+	if c, ok := cassettes[path]; ok {
+		return c, nil
+	}
+	
+	c := &cassette{path: path, mode: mode}
+	if mode == CassetteReplay {
+		if err := c.load(); err != nil {
+			return nil, err
+		}
+	}
+	cassettes[path] = c
+	return c, nil
+}
+
+func (c *cassette) load() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("load cassette: %v", err)
+	}
+	
+	var file struct {
+		Interactions []*cassetteInteraction `json:"interactions"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("load cassette %s: %v", c.path, err)
+	}
+	c.interactions = file.Interactions
+	return nil
+}
+
+// save 将全部记录写入磁带文件
+func (c *cassette) save() error {
+	data, err := json.MarshalIndent(struct {
+		Interactions []*cassetteInteraction `json:"interactions"`
+	}{c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(c.path, data, 0600)
+}
+
+// record 追加一次交互并立即落盘
+func (c *cassette) record(interaction *cassetteInteraction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	c.interactions = append(c.interactions, interaction)
+	return c.save()
+}
+
+// match 返回与请求对应的下一条未使用的记录：优先匹配方法、URL 与请求体完全一致的记录，
+// 找不到时按录制顺序取方法与 URL 一致的记录，以容忍请求体中随时间变化的内容
+func (c *cassette) match(method, url, body string) (*cassetteInteraction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	var fallback *cassetteInteraction
+	for _, it := range c.interactions {
+		if it.used || it.Request.Method != method || it.Request.URL != url {
+			continue
+		}
+		if it.Request.Body == body {
+			it.used = true
+			return it, true
+		}
+		if fallback == nil {
+			fallback = it
+		}
+	}
+	
+	if fallback == nil {
+		return nil, false
+	}
+	fallback.used = true
+	return fallback, true
+}
+
+// cassetteTransport 在录制模式下透传请求并记录，在回放模式下直接返回记录的响应。
+// 配置中的密钥、认证相关的请求头与查询参数，以及 JSON 中名称与认证相关的字段
+// （如百炼 CreateToken 返回的临时 Token）在写入磁带前会被替换为 REDACTED
+type cassetteTransport struct {
+	base     http.RoundTripper
+	cassette *cassette
+	secrets  []string
+}
+
+func newCassetteTransport(base http.RoundTripper, c *cassette, config types.APIConfig) *cassetteTransport {
+	t := &cassetteTransport{base: base, cassette: c}
+	for _, secret := range []string{config.APIKey, config.AuthKey, config.AccessKeyID, config.AccessKeySecret} {
+		// 过短的值（如 auth_key 中的请求头名称）替换后会误伤正常内容
+		if len(secret) >= 8 {
+			t.secrets = append(t.secrets, secret)
+		}
+	}
+	return t
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	
+	reqURL := t.redact(redactQuery(req.URL))
+	reqBody := t.redactBody(body)
+	
+	if t.cassette.mode == CassetteReplay {
+		it, ok := t.cassette.match(req.Method, reqURL, reqBody)
+		if !ok {
+			return nil, fmt.Errorf("cassette %s has no recorded response for %s %s", t.cassette.path, req.Method, reqURL)
+		}
+		return &http.Response{
+			StatusCode:    it.Response.StatusCode,
+			Status:        it.Response.Status,
+			Header:        http.Header(it.Response.Headers).Clone(),
+			Body:          io.NopCloser(strings.NewReader(it.Response.Body)),
+			ContentLength: int64(len(it.Response.Body)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Request:       req,
+		}, nil
+	}
+	
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	
+	it := &cassetteInteraction{}
+	it.Request.Method = req.Method
+	it.Request.URL = reqURL
+	it.Request.Headers = t.redactHeaders(req.Header)
+	it.Request.Body = reqBody
+	it.Response.StatusCode = resp.StatusCode
+	it.Response.Status = resp.Status
+	it.Response.Headers = t.redactHeaders(resp.Header)
+	
+	// 响应体边读边转发，流式输出不受影响；读完或关闭时若响应完整则写入磁带
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		done: func(data []byte, complete bool) {
+			// 被取消或中断的响应不写入磁带，否则回放时会被当作完整的回复
+			if !complete {
+				return
+			}
+			it.Response.Body = t.redactBody(data)
+			if err := t.cassette.record(it); err != nil {
+				// 响应已交给调用方，无法再返回错误，提示磁带缺少这次交互
+				fmt.Fprintf(os.Stderr, "cassette: failed to save %s: %v\n", t.cassette.path, err)
+			}
+		},
+	}
+	return resp, nil
+}
+
+// redact 替换文本中出现的密钥
+func (t *cassetteTransport) redact(text string) string {
+	for _, secret := range t.secrets {
+		text = strings.ReplaceAll(text, secret, redacted)
+	}
+	return text
+}
+
+// redactBody 替换请求或响应体中的密钥；JSON 中名称与认证相关的字符串字段
+// 在运行时才获得（如临时 token），无法预先列出，一并替换。没有需要替换的字段时保留原文
+func (t *cassetteTransport) redactBody(data []byte) string {
+	text := t.redact(string(data))
+	
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var value interface{}
+	if decoder.Decode(&value) != nil || decoder.More() || !redactJSON(value) {
+		return text
+	}
+	
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if encoder.Encode(value) != nil {
+		return text
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactJSON 就地替换名称与认证相关的字符串字段，返回是否有改动
+func redactJSON(value interface{}) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			if s, ok := field.(string); ok && s != "" && s != redacted && isSensitiveHeader(name) {
+				v[name] = redacted
+				changed = true
+				continue
+			}
+			changed = redactJSON(field) || changed
+		}
+	case []interface{}:
+		for _, item := range v {
+			changed = redactJSON(item) || changed
+		}
+	}
+	return changed
+}
+
+// redactHeaders 复制请求头，并替换认证相关头的值
+func (t *cassetteTransport) redactHeaders(header http.Header) map[string][]string {
+	result := make(map[string][]string, len(header))
+	for name, values := range header {
+		if isSensitiveHeader(name) {
+			result[name] = []string{redacted}
+			continue
+		}
+		copied := make([]string, len(values))
+		for i, v := range values {
+			copied[i] = t.redact(v)
+		}
+		result[name] = copied
+	}
+	return result
+}
+
+func isSensitiveHeader(name string) bool {
+	lower := strings.ToLower(name)
+	switch lower {
+	case "authorization", "proxy-authorization", "cookie", "set-cookie":
+		return true
+	case "idempotency-key":
+		return false
+	}
+	for _, word := range []string{"key", "token", "secret", "signature"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactQuery 返回查询参数中认证相关的值已被替换的 URL
+func redactQuery(u *url.URL) string {
+	query := u.Query()
+	changed := false
+	for name := range query {
+		if isSensitiveHeader(name) {
+			query.Set(name, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	
+	copied := *u
+	copied.RawQuery = query.Encode()
+	return copied.String()
+}
+
+// recordingBody 在转发响应体的同时保留一份副本，读到结尾或关闭时交给 done，只调用一次。
+// complete 表示响应体是否完整，见 responseComplete
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func(data []byte, complete bool)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish(true)
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	// 不读取剩余内容：流式响应被中断后继续读取可能长时间阻塞
+	b.finish(responseComplete(b.buf.Bytes()))
+	return b.ReadCloser.Close()
+}
+
+// responseComplete 判断未读到结尾就关闭的响应体是否已经完整：
+// 解码 JSON 后通常只剩结尾的换行未读，流式响应在收到 data: [DONE] 后即停止读取
+func responseComplete(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if json.Valid(trimmed) {
+		return true
+	}
+	return bytes.HasSuffix(trimmed, []byte("data: [DONE]"))
+}
+
+func (b *recordingBody) finish(complete bool) {
+	b.once.Do(func() {
+		b.done(b.buf.Bytes(), complete)
+	})
+}
@@ -0,0 +1,197 @@
+package providers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+const stubAPIKey = "sk-stub-api-key-0123"
+
+func doCassetteRequest(t *testing.T, client *http.Client, url, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+stubAPIKey)
+	req.Header.Set("X-Session-Token", "session-token-value")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(data)
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "sid=server-cookie")
+		io.WriteString(w, `{"reply":"hello <world>","access_token":"runtime-token-xyz","count":12345678901234567890}`)
+	}))
+	defer srv.Close()
+	
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	config := types.APIConfig{APIKey: stubAPIKey}
+	reqURL := srv.URL + "/v1/chat?api_key=query-key-value&model=m1"
+	reqBody := `{"model":"m1","api_key":"` + stubAPIKey + `","refresh_token":"body-token-value","prompt":"hi"}`
+	
+	recorder := &http.Client{Transport: newCassetteTransport(http.DefaultTransport, &cassette{path: path, mode: CassetteRecord}, config)}
+	_, recorded := doCassetteRequest(t, recorder, reqURL, reqBody)
+	
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{stubAPIKey, "session-token-value", "query-key-value", "body-token-value", "runtime-token-xyz", "server-cookie"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+	player := &cassette{path: path, mode: CassetteReplay}
+	if err := player.load(); err != nil {
+		t.Fatal(err)
+	}
+	
+	// 未改动的内容原样保留：大整数不丢精度，HTML 字符不转义
+	recordedIt := player.interactions[0]
+	for _, kept := range []struct{ text, want string }{
+		{recordedIt.Request.URL, "model=m1"},
+		{recordedIt.Request.Body, `"prompt":"hi"`},
+		{recordedIt.Response.Body, "12345678901234567890"},
+		{recordedIt.Response.Body, "hello <world>"},
+	} {
+		if !strings.Contains(kept.text, kept.want) {
+			t.Errorf("cassette lost %q: %s", kept.want, kept.text)
+		}
+	}
+	
+	replayer := &http.Client{Transport: newCassetteTransport(http.DefaultTransport, player, config)}
+	resp, replayed := doCassetteRequest(t, replayer, reqURL, reqBody)
+	if calls != 1 {
+		t.Errorf("server called %d times, want 1", calls)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("replayed status %d, headers %v", resp.StatusCode, resp.Header)
+	}
+	if !strings.Contains(recorded, "runtime-token-xyz") {
+		t.Errorf("recording changed the live response: %s", recorded)
+	}
+	if replayed != recordedIt.Response.Body || !strings.Contains(replayed, `"access_token":"REDACTED"`) {
+		t.Errorf("replayed body = %s", replayed)
+	}
+	
+	// 磁带中没有对应的记录时报错，而不是访问网络
+	_, err = replayer.Post(srv.URL+"/v1/other", "application/json", strings.NewReader("{}"))
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("replay miss error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("server called %d times after replay miss, want 1", calls)
+	}
+}
+
+func TestCassetteMatch(t *testing.T) {
+	newInteraction := func(method, url, body string) *cassetteInteraction {
+		it := &cassetteInteraction{}
+		it.Request.Method, it.Request.URL, it.Request.Body = method, url, body
+		it.Response.Body = body
+		return it
+	}
+	c := &cassette{interactions: []*cassetteInteraction{
+		newInteraction("POST", "/chat", "a"),
+		newInteraction("POST", "/chat", "b"),
+		newInteraction("GET", "/models", ""),
+		newInteraction("POST", "/chat", "c"),
+	}}
+	
+	// 依次执行，后面的请求受前面已使用的记录影响
+	tests := []struct {
+		method, url, body string
+		want              string // 返回记录的请求体，"-" 表示没有匹配
+	}{
+		{"POST", "/chat", "b", "b"},       // 请求体完全一致优先
+		{"POST", "/chat", "changed", "a"}, // 否则按录制顺序取方法与 URL 一致的记录
+		{"POST", "/chat", "b", "c"},       // 已使用的记录不再返回
+		{"GET", "/chat", "", "-"},         // 方法不同
+		{"GET", "/models", "", ""},
+		{"POST", "/chat", "a", "-"}, // 全部用完
+	}
+	for i, tt := range tests {
+		it, ok := c.match(tt.method, tt.url, tt.body)
+		got := "-"
+		if ok {
+			got = it.Request.Body
+		}
+		if got != tt.want {
+			t.Errorf("step %d: match(%s %s %q) = %q, want %q", i, tt.method, tt.url, tt.body, got, tt.want)
+		}
+	}
+}
+
+// 中途关闭的流式响应不完整，不应写入磁带
+func TestCassetteSkipsIncompleteResponses(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/done" {
+			io.WriteString(w, "data: [DONE]\n\n")
+			return
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	
+	c := &cassette{path: filepath.Join(t.TempDir(), "cassette.json"), mode: CassetteRecord}
+	client := &http.Client{Transport: newCassetteTransport(http.DefaultTransport, c, types.APIConfig{})}
+	
+	tests := []struct {
+		path     string
+		recorded int // 请求后磁带中的交互数
+	}{
+		{"/interrupted", 0},
+		{"/done", 1},
+	}
+	for _, tt := range tests {
+		resp, err := client.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 只读取第一个事件就关闭，与用户中断流式输出相同
+		buf := make([]byte, 16)
+		if _, err := io.ReadFull(resp.Body, buf); err != nil {
+			t.Fatal(err)
+		}
+		if tt.path == "/done" {
+			io.ReadAll(resp.Body)
+		}
+		resp.Body.Close()
+		
+		c.mu.Lock()
+		got := len(c.interactions)
+		c.mu.Unlock()
+		if got != tt.recorded {
+			t.Errorf("after %s cassette has %d interactions, want %d", tt.path, got, tt.recorded)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// formatMode 是接口支持的 response_format 类型
+type formatMode int
+
+const (
+	formatNone       formatMode = iota
+	formatJSONObject            // JSON 模式，只保证回复是 JSON 对象
+	formatJSONSchema            // 按 JSON Schema 严格约束
+)
+
+// chatRequest 描述一次 OpenAI 兼容 chat completions 请求中与供应商无关的部分
+type chatRequest struct {
+	Messages []types.Message
+	Tools    []types.Tool
+	Stream   bool
+	Params   types.GenerationParams
+	
+	// StreamUsage 要求流式响应在最后一个片段中附带 usage，
+	// 仅用于支持 stream_options 的接口
+	StreamUsage bool
+	
+	// Format 是要求的输出格式，按 FormatMode 写入 response_format
+	Format     *types.ResponseFormat
+	FormatMode formatMode
+}
+
+// apply 将对话、工具、生成参数、输出格式与流式选项写入请求体
+func (c chatRequest) apply(requestBody map[string]interface{}) {
+	requestBody["messages"] = toChatMessages(c.Messages)
+	applyGenerationParams(requestBody, c.Params)
+	
+	if len(c.Tools) > 0 {
+		tools := make([]map[string]interface{}, 0, len(c.Tools))
+		for _, tool := range c.Tools {
+			tools = append(tools, map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        tool.Name,
+					"description": tool.Description,
+					"parameters":  tool.Parameters,
+				},
+			})
+		}
+		requestBody["tools"] = tools
+	}
+	
+	if c.Format != nil {
+		switch c.FormatMode {
+		case formatJSONSchema:
+			requestBody["response_format"] = map[string]interface{}{
+				"type": "json_schema",
+				"json_schema": map[string]interface{}{
+					"name":   c.Format.Name,
+					"schema": c.Format.Schema,
+					"strict": true,
+				},
+			}
+		case formatJSONObject:
+			requestBody["response_format"] = map[string]interface{}{"type": "json_object"}
+		}
+	}
+	
+	if c.Stream {
+		requestBody["stream"] = true
+		if c.StreamUsage {
+			requestBody["stream_options"] = map[string]interface{}{"include_usage": true}
+		}
+	}
+}
+
+// chatMessage 是 OpenAI 兼容接口的消息格式
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    chatContent    `json:"content"`
+	Name       string         `json:"name,omitempty"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	
+	// ReasoningContent 只出现在推理模型的响应中，请求中不回传
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// chatContent 是消息内容：纯文本序列化为字符串，
+// 带图片时序列化为 content parts 数组，图片以 base64 data URL 内联
+type chatContent struct {
+	Text   string
+	Images []types.Image
+}
+
+func (c chatContent) MarshalJSON() ([]byte, error) {
+	if len(c.Images) == 0 {
+		return json.Marshal(c.Text)
+	}
+	
+	parts := make([]map[string]interface{}, 0, len(c.Images)+1)
+	if c.Text != "" {
+		parts = append(parts, map[string]interface{}{"type": "text", "text": c.Text})
+	}
+	for _, img := range c.Images {
+		parts = append(parts, map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]string{"url": img.DataURL()},
+		})
+	}
+	return json.Marshal(parts)
+}
+
+// UnmarshalJSON 接受字符串、null 或 content parts 数组，数组中只保留文本部分
+func (c *chatContent) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		c.Text = ""
+		return nil
+	}
+	if err := json.Unmarshal(data, &c.Text); err == nil {
+		return nil
+	}
+	
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	c.Text = ""
+	for _, part := range parts {
+		if part.Type == "text" {
+			c.Text += part.Text
+		}
+	}
+	return nil
+}
+
+// chatUsage 是 OpenAI 兼容接口返回的 usage 字段，推理 token 位于 completion_tokens_details 中。
+// 缓存命中的输入 token 在 OpenAI 中位于 prompt_tokens_details，DeepSeek 则是 prompt_cache_hit_tokens
+type chatUsage struct {
+	PromptTokens            int `json:"prompt_tokens"`
+	CompletionTokens        int `json:"completion_tokens"`
+	TotalTokens             int `json:"total_tokens"`
+	PromptCacheHitTokens    int `json:"prompt_cache_hit_tokens"`
+	PromptTokensDetails     struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+func (u chatUsage) usage() types.Usage {
+	return types.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		ReasoningTokens:  u.CompletionTokensDetails.ReasoningTokens,
+		CachedTokens:     max(u.PromptCacheHitTokens, u.PromptTokensDetails.CachedTokens),
+	}
+}
+
+type chatToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// toChatMessages 将通用消息转换为 OpenAI 兼容格式
+func toChatMessages(messages []types.Message) []chatMessage {
+	result := make([]chatMessage, 0, len(messages))
+	for _, msg := range messages {
+		cm := chatMessage{
+			Role:       msg.Role,
+			Content:    chatContent{Text: msg.Content, Images: msg.Images},
+			Name:       msg.Name,
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, call := range msg.ToolCalls {
+			tc := chatToolCall{ID: call.ID, Type: "function"}
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = call.Arguments
+			cm.ToolCalls = append(cm.ToolCalls, tc)
+		}
+		result = append(result, cm)
+	}
+	return result
+}
+
+// decodeChatResponse 解析非流式 chat completions 响应，包括工具调用、思考过程、结束原因与用量
+func decodeChatResponse(r io.Reader, name string) (types.Response, error) {
+	var response struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message      chatMessage `json:"message"`
+			FinishReason string      `json:"finish_reason"`
+		} `json:"choices"`
+		Usage chatUsage `json:"usage"`
+	}
+	
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return types.Response{}, err
+	}
+	
+	if len(response.Choices) == 0 {
+		return types.Response{}, fmt.Errorf("no completions received from %s", name)
+	}
+	
+	choice := response.Choices[0]
+	if choice.FinishReason == "content_filter" {
+		return types.Response{}, fmt.Errorf("%s response was blocked by the content filter", name)
+	}
+	
+	result := types.Response{
+		Content:      choice.Message.Content.Text,
+		Reasoning:    choice.Message.ReasoningContent,
+		FinishReason: choice.FinishReason,
+		Model:        response.Model,
+		Usage:        response.Usage.usage(),
+	}
+	for _, call := range choice.Message.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, types.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return result, nil
+}
+
+// withModel 在响应未携带模型 ID 时填入配置的模型
+func withModel(response types.Response, model string) types.Response {
+	if response.Model == "" {
+		response.Model = model
+	}
+	return response
+}
+
+// withEstimatedUsage 在响应没有附带用量时按请求与回复的字符估算，并标记为估算值
+func withEstimatedUsage(response types.Response, messages []types.Message) types.Response {
+	if response.Usage.PromptTokens > 0 || response.Usage.CompletionTokens > 0 {
+		return response
+	}
+	prompt := 0
+	for _, msg := range messages {
+		prompt += estimateRequestTokens([]byte(msg.Content)) + len(msg.Images)*imageTokenEstimate
+	}
+	completion := estimateRequestTokens([]byte(response.Content))
+	response.Usage = types.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		TotalTokens:      prompt + completion,
+		Estimated:        true,
+	}
+	return response
+}
@@ -0,0 +1,326 @@
+package providers
+
+import (
+	"context"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+const (
+	// defaultCustomRequestTemplate 未配置模板时按 OpenAI 兼容格式发送
+	defaultCustomRequestTemplate = `{"model": {{json .Model}}, "messages": {{json .Messages}}{{if .MaxTokens}}, "max_tokens": {{.MaxTokens}}{{end}}}`
+	defaultCustomResponsePath    = "$.choices[0].message.content"
+)
+
+func init() {
+	Register(Registration{
+		Name:        "custom",
+		Description: "自定义 HTTP 网关 (模板化请求与响应)",
+		Validate:    validateCustomConfig,
+		Fields:      []string{"api_base", "model", "auth_type", "api_key", "auth_key", "request_template", "response_path"},
+		New: func(config types.APIConfig) (types.AIProvider, error) {
+			return NewCustomProvider(config)
+		},
+	})
+}
+
+// CustomProvider 通过模板对接任意 HTTP 网关
+type CustomProvider struct {
+	config  types.APIConfig
+	client  *http.Client
+	body    *template.Template
+	headers map[string]*template.Template
+}
+
+// customTemplateData 是请求体与请求头模板可用的字段
+type customTemplateData struct {
+	Messages  []types.Message
+	Prompt    string // 对话渲染成的单段文本，供只接受 prompt 的网关使用
+	Model     string
+	MaxTokens int
+	APIKey    string
+	AuthKey   string
+	
+	// Params 是已设置的生成参数，键为 OpenAI 风格的参数名，如 {{with .Params.temperature}}
+	Params map[string]interface{}
+}
+
+var customTemplateFuncs = template.FuncMap{
+	// json 将值编码为 JSON 字面量，用于在模板中安全嵌入字符串
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// validateCustomConfig 检查 custom 配置的必填字段
+func validateCustomConfig(config types.APIConfig) error {
+	if config.APIBase == "" {
+		return fmt.Errorf("custom provider requires APIBase")
+	}
+	
+	switch config.AuthType {
+	case "", "none", "bearer":
+	case "header":
+		if config.AuthKey == "" {
+			return fmt.Errorf("custom provider header auth requires AuthKey (header name)")
+		}
+	default:
+		return fmt.Errorf("unsupported custom auth type: %s", config.AuthType)
+	}
+	return nil
+}
+
+func NewCustomProvider(config types.APIConfig) (*CustomProvider, error) {
+	if err := validateCustomConfig(config); err != nil {
+		return nil, err
+	}
+	
+	bodyText := config.RequestTemplate
+	if bodyText == "" {
+		bodyText = defaultCustomRequestTemplate
+	}
+	body, err := template.New("body").Funcs(customTemplateFuncs).Parse(bodyText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request template: %v", err)
+	}
+	
+	headers := make(map[string]*template.Template, len(config.Headers))
+	for name, value := range config.Headers {
+		tmpl, err := template.New(name).Funcs(customTemplateFuncs).Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header template %s: %v", name, err)
+		}
+		headers[name] = tmpl
+	}
+	
+	if config.ResponsePath == "" {
+		config.ResponsePath = defaultCustomResponsePath
+	}
+	if _, err := parseJSONPath(config.ResponsePath); err != nil {
+		return nil, err
+	}
+	
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &CustomProvider{
+		config:  config,
+		client:  client,
+		body:    body,
+		headers: headers,
+	}, nil
+}
+
+func (p *CustomProvider) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	if err := checkImages(p, messages); err != nil {
+		return types.Response{}, err
+	}
+	
+	data := customTemplateData{
+		Messages:  messages,
+		Prompt:    flattenMessages(messages),
+		Model:     p.config.Model,
+		MaxTokens: p.config.MaxTokens,
+		APIKey:    p.config.APIKey,
+		AuthKey:   p.config.AuthKey,
+		Params:    map[string]interface{}{},
+	}
+	applyGenerationParams(data.Params, generationParams(ctx, p.config.GenerationParams))
+	
+	var body bytes.Buffer
+	if err := p.body.Execute(&body, data); err != nil {
+		return types.Response{}, fmt.Errorf("render request template: %v", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, "POST", p.config.APIBase, &body)
+	if err != nil {
+		return types.Response{}, err
+	}
+	
+	req.Header.Set("Content-Type", "application/json")
+	if err := p.setHeaders(req, data); err != nil {
+		return types.Response{}, err
+	}
+	
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, err
+	}
+	defer resp.Body.Close()
+	
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return types.Response{}, err
+	}
+	
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return types.Response{}, &APIError{Provider: p.GetName(), StatusCode: resp.StatusCode, Status: resp.Status, Body: string(respBody)}
+	}
+	
+	var payload interface{}
+	if err := json.Unmarshal(respBody, &payload); err != nil {
+		return types.Response{}, fmt.Errorf("%s returned invalid JSON: %v", p.GetName(), err)
+	}
+	
+	value, err := extractJSONPath(payload, p.config.ResponsePath)
+	if err != nil {
+		return types.Response{}, fmt.Errorf("%s response: %v", p.GetName(), err)
+	}
+	
+	var content string
+	switch v := value.(type) {
+	case string:
+		content = v
+	case nil:
+		return types.Response{}, fmt.Errorf("%s response: %s is null", p.GetName(), p.config.ResponsePath)
+	default:
+		// 非字符串结果原样返回其 JSON 表示
+		encoded, _ := json.Marshal(v)
+		content = string(encoded)
+	}
+	
+	// 响应采用 OpenAI 兼容格式时顺带读取模型与用量，其他格式保持零值
+	var meta struct {
+		Model string    `json:"model"`
+		Usage chatUsage `json:"usage"`
+	}
+	json.Unmarshal(respBody, &meta)
+	
+	return withModel(types.Response{
+		Content: content,
+		Model:   meta.Model,
+		Usage:   meta.Usage.usage(),
+	}, p.GetModel()), nil
+}
+
+// setHeaders 按 AuthType 设置认证头，再渲染额外的请求头模板
+func (p *CustomProvider) setHeaders(req *http.Request, data customTemplateData) error {
+	switch p.config.AuthType {
+	case "", "bearer":
+		if p.config.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+		}
+	case "header":
+		req.Header.Set(p.config.AuthKey, p.config.APIKey)
+	}
+	
+	for name, tmpl := range p.headers {
+		var value bytes.Buffer
+		if err := tmpl.Execute(&value, data); err != nil {
+			return fmt.Errorf("render header %s: %v", name, err)
+		}
+		req.Header.Set(name, value.String())
+	}
+	return nil
+}
+
+// jsonPathStep 是路径中的一段：对象键或数组下标
+type jsonPathStep struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// parseJSONPath 解析 $.a.b[0].c 形式的简化 JSONPath
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var steps []jsonPathStep
+	
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid response path %q: empty key", path)
+			}
+			steps = append(steps, jsonPathStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid response path %q: missing ]", path)
+			}
+			inner := strings.Trim(rest[1:end], `'"`)
+			if idx, err := strconv.Atoi(inner); err == nil {
+				steps = append(steps, jsonPathStep{index: idx, isIdx: true})
+			} else {
+				steps = append(steps, jsonPathStep{key: inner})
+			}
+			rest = rest[end+1:]
+		default:
+			// 允许省略开头的 "$."
+			rest = "." + rest
+		}
+	}
+	
+	return steps, nil
+}
+
+// extractJSONPath 沿路径取出解码后 JSON 中的值
+func extractJSONPath(data interface{}, path string) (interface{}, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	
+	current := data
+	for _, step := range steps {
+		if step.isIdx {
+			arr, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: expected array before [%d]", path, step.index)
+			}
+			idx := step.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, fmt.Errorf("%s: index %d out of range", path, step.index)
+			}
+			current = arr[idx]
+			continue
+		}
+		
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: expected object before %q", path, step.key)
+		}
+		value, exists := obj[step.key]
+		if !exists {
+			return nil, fmt.Errorf("%s: key %q not found", path, step.key)
+		}
+		current = value
+	}
+	
+	return current, nil
+}
+
+func (p *CustomProvider) GetName() string {
+	if p.config.Name != "" {
+		return p.config.Name
+	}
+	return "Custom"
+}
+
+func (p *CustomProvider) GetModel() string {
+	return p.config.Model
+}
+
+func (p *CustomProvider) SupportsFeature(feature types.Capability) bool {
+	return false
+}
@@ -0,0 +1,193 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []jsonPathStep
+		wantErr string
+	}{
+		{"$", nil, ""},
+		{"", nil, ""},
+		{"$.a", []jsonPathStep{{key: "a"}}, ""},
+		{"$.choices[0].message.content", []jsonPathStep{{key: "choices"}, {index: 0, isIdx: true}, {key: "message"}, {key: "content"}}, ""},
+		{"output.text", []jsonPathStep{{key: "output"}, {key: "text"}}, ""},
+		{"$['a.b'][\"c\"]", []jsonPathStep{{key: "a.b"}, {key: "c"}}, ""},
+		{"$.items[-1]", []jsonPathStep{{key: "items"}, {index: -1, isIdx: true}}, ""},
+		{" $.a ", []jsonPathStep{{key: "a"}}, ""},
+		{"$.a..b", nil, "empty key"},
+		{"$.a[0", nil, "missing ]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseJSONPath(%q) error = %v, want %q", tt.path, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJSONPath(%q) = %+v, want %+v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractJSONPath(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{
+		"choices": [{"message": {"content": "hello"}}, {"message": {"content": "bye"}}],
+		"usage": {"total_tokens": 12},
+		"a.b": "dotted",
+		"empty": null
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	
+	tests := []struct {
+		path    string
+		want    interface{}
+		wantErr string
+	}{
+		{"$.choices[0].message.content", "hello", ""},
+		{"$.choices[-1].message.content", "bye", ""},
+		{"usage.total_tokens", float64(12), ""},
+		{"$['a.b']", "dotted", ""},
+		{"$.empty", nil, ""},
+		{"$.choices[2]", nil, "out of range"},
+		{"$.choices[-3]", nil, "out of range"},
+		{"$.missing", nil, "not found"},
+		{"$.usage[0]", nil, "expected array"},
+		{"$.choices.message", nil, "expected object"},
+		{"$.a..b", nil, "empty key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := extractJSONPath(data, tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extractJSONPath(%q) error = %v, want %q", tt.path, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractJSONPath(%q) = %#v, want %#v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomProviderRequest(t *testing.T) {
+	temperature := 0.3
+	tests := []struct {
+		name     string
+		config   types.APIConfig
+		response string
+		body     string            // 期望的请求体
+		headers  map[string]string // 期望的请求头，空字符串表示不应出现
+		content  string
+	}{
+		{
+			name:     "default template with bearer auth",
+			config:   types.APIConfig{Model: "m1", MaxTokens: 64, APIKey: stubAPIKey},
+			response: `{"model":"m1-0601","choices":[{"message":{"content":"hello"}}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`,
+			body:     `{"model":"m1","messages":[{"role":"user","content":"say \"hi\""}],"max_tokens":64}`,
+			headers:  map[string]string{"Authorization": "Bearer " + stubAPIKey},
+			content:  "hello",
+		},
+		{
+			name: "custom template with json and params",
+			config: types.APIConfig{
+				Model:            "m2",
+				AuthType:         "none",
+				APIKey:           stubAPIKey,
+				RequestTemplate:  `{"input": {{json .Prompt}}, "model": {{json .Model}}{{with .Params.temperature}}, "temperature": {{.}}{{end}}{{with .Params.top_p}}, "top_p": {{.}}{{end}}}`,
+				ResponsePath:     "$.output.text",
+				GenerationParams: types.GenerationParams{Temperature: &temperature},
+			},
+			response: `{"output":{"text":"done"}}`,
+			body:     `{"input":"say \"hi\"","model":"m2","temperature":0.3}`,
+			headers:  map[string]string{"Authorization": ""},
+			content:  "done",
+		},
+		{
+			name: "header auth with header templates",
+			config: types.APIConfig{
+				Model:    "m3",
+				AuthType: "header",
+				AuthKey:  "X-Api-Key",
+				APIKey:   stubAPIKey,
+				Headers:  map[string]string{"X-Model": "{{.Model}}", "X-Signature": "key={{.APIKey}}"},
+			},
+			response: `{"choices":[{"message":{"content":"ok"}}]}`,
+			body:     `{"model":"m3","messages":[{"role":"user","content":"say \"hi\""}]}`,
+			headers: map[string]string{
+				"X-Api-Key":     stubAPIKey,
+				"X-Model":       "m3",
+				"X-Signature":   "key=" + stubAPIKey,
+				"Authorization": "",
+			},
+			content: "ok",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody []byte
+			var gotHeader http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotBody, _ = io.ReadAll(r.Body)
+				gotHeader = r.Header
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, tt.response)
+			}))
+			defer srv.Close()
+			
+			config := tt.config
+			config.Provider, config.APIBase, config.MaxRetries = "custom", srv.URL, -1
+			p, err := NewCustomProvider(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := p.SendRequest(context.Background(), userMessage(`say "hi"`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			
+			var got, want interface{}
+			if err := json.Unmarshal(gotBody, &got); err != nil {
+				t.Fatalf("request body is not JSON: %v\n%s", err, gotBody)
+			}
+			json.Unmarshal([]byte(tt.body), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("request body = %s, want %s", gotBody, tt.body)
+			}
+			for name, value := range tt.headers {
+				if gotHeader.Get(name) != value {
+					t.Errorf("header %s = %q, want %q", name, gotHeader.Get(name), value)
+				}
+			}
+			if response.Content != tt.content {
+				t.Errorf("content = %q, want %q", response.Content, tt.content)
+			}
+		})
+	}
+}
@@ -2,40 +2,76 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	
 	"github.com/yantianyv/AkashaTerminal/pkg/types"
 )
 
+func init() {
+	Register(Registration{
+		Name:        "deepseek",
+		Description: "DeepSeek",
+		Validate:    validateDeepSeekConfig,
+		Fields:      []string{"api_key", "api_base", "model", "max_tokens"},
+		New: func(config types.APIConfig) (types.AIProvider, error) {
+			return NewDeepSeekProvider(config)
+		},
+	})
+}
+
 type DeepSeekProvider struct {
 	config types.APIConfig
+	client *http.Client
 }
 
-func NewProvider(config types.APIConfig) (*DeepSeekProvider, error) {
+// validateDeepSeekConfig 检查 deepseek 配置的必填字段
+func validateDeepSeekConfig(config types.APIConfig) error {
 	if config.APIKey == "" {
-		return nil, fmt.Errorf("DeepSeek API key is required")
+		return fmt.Errorf("DeepSeek API key is required")
+	}
+	return nil
+}
+
+func NewDeepSeekProvider(config types.APIConfig) (*DeepSeekProvider, error) {
+	if err := validateDeepSeekConfig(config); err != nil {
+		return nil, err
+	}
+	
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
 	}
 	
 	return &DeepSeekProvider{
 		config: config,
+		client: client,
 	}, nil
 }
 
-func (p *DeepSeekProvider) SendRequest(prompt string, state interface{}) (string, error) {
-	url := "https://api.deepseek.com/v1/chat/completions"
+// endpoint 返回 chat completions 地址，APIBase 可覆盖默认值
+func (p *DeepSeekProvider) endpoint() string {
 	if p.config.APIBase != "" {
-		url = p.config.APIBase
+		return p.config.APIBase
+	}
+	return "https://api.deepseek.com/v1/chat/completions"
+}
+
+// newRequest 构造 chat completions 请求
+func (p *DeepSeekProvider) newRequest(ctx context.Context, chat chatRequest) (*http.Request, error) {
+	if err := checkImages(p, chat.Messages); err != nil {
+		return nil, err
 	}
 	
+	chat.Params = generationParams(ctx, p.config.GenerationParams)
+	chat.FormatMode = p.formatMode()
+	// DeepSeek 不支持 seed
+	chat.Params.Seed = nil
+	
 	requestBody := map[string]interface{}{
 		"model": p.config.Model,
-		"messages": []interface{}{
-			{"role": "system", "content": "You are a helpful coding assistant"},
-			{"role": "user", "content": prompt},
-		},
 		"max_tokens": p.config.MaxTokens,
 	}
 	
@@ -43,48 +79,111 @@ func (p *DeepSeekProvider) SendRequest(prompt string, state interface{}) (string
 		requestBody["version"] = p.config.Version
 	}
 	
+	chat.apply(requestBody)
+	
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint(), bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	return req, nil
+}
+
+func (p *DeepSeekProvider) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	return p.SendWithTools(ctx, messages, nil)
+}
+
+func (p *DeepSeekProvider) StreamRequest(ctx context.Context, messages []types.Message, onChunk func(string)) (types.Response, error) {
+	return p.stream(ctx, chatRequest{Messages: messages, Stream: true, StreamUsage: true}, onChunk)
+}
+
+// stream 发送流式请求，每收到一段文本调用一次 onChunk
+func (p *DeepSeekProvider) stream(ctx context.Context, chat chatRequest, onChunk func(string)) (types.Response, error) {
+	req, err := p.newRequest(ctx, chat)
+	if err != nil {
+		return types.Response{}, err
+	}
 	
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", err
+		return types.Response{}, err
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("DeepSeek API error: %s - %s", resp.Status, string(body))
+		return types.Response{}, newAPIError("DeepSeek", resp)
 	}
 	
-	var response struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+	response, err := readChatStream(resp.Body, onChunk, reasoningHandler(ctx))
+	if err != nil {
+		return types.Response{}, err
+	}
+	return withModel(response, p.GetModel()), nil
+}
+
+func (p *DeepSeekProvider) SendStructured(ctx context.Context, messages []types.Message, format types.ResponseFormat, onChunk func(string)) (types.Response, error) {
+	if onChunk == nil {
+		return p.send(ctx, chatRequest{Messages: messages, Format: &format})
+	}
+	return p.stream(ctx, chatRequest{Messages: messages, Stream: true, StreamUsage: true, Format: &format}, onChunk)
+}
+
+func (p *DeepSeekProvider) StreamWithTools(ctx context.Context, messages []types.Message, tools []types.Tool, onChunk func(string)) (types.Response, error) {
+	return p.stream(ctx, chatRequest{Messages: messages, Tools: tools, Stream: true, StreamUsage: true}, onChunk)
+}
+
+func (p *DeepSeekProvider) SendWithTools(ctx context.Context, messages []types.Message, tools []types.Tool) (types.Response, error) {
+	return p.send(ctx, chatRequest{Messages: messages, Tools: tools})
+}
+
+// send 发送一次性请求
+func (p *DeepSeekProvider) send(ctx context.Context, chat chatRequest) (types.Response, error) {
+	req, err := p.newRequest(ctx, chat)
+	if err != nil {
+		return types.Response{}, err
 	}
 	
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", err
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, err
 	}
+	defer resp.Body.Close()
 	
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no completions received from DeepSeek")
+	if resp.StatusCode != http.StatusOK {
+		return types.Response{}, newAPIError("DeepSeek", resp)
 	}
 	
-	return response.Choices[0].Message.Content, nil
+	response, err := decodeChatResponse(resp.Body, "DeepSeek")
+	if err != nil {
+		return types.Response{}, err
+	}
+	return withModel(response, p.GetModel()), nil
+}
+
+// ListModels 列出账号可用的模型
+func (p *DeepSeekProvider) ListModels(ctx context.Context) ([]types.ModelInfo, error) {
+	return listModels(ctx, p.client, modelsURL(p.endpoint()), p.config.APIKey, "DeepSeek")
+}
+
+// Embed 使用向量模型将文本转换为向量。
+// DeepSeek 官方接口不提供向量模型，需在 embedding_model 中指定兼容服务上的模型
+func (p *DeepSeekProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if p.config.EmbeddingModel == "" {
+		return nil, fmt.Errorf("DeepSeek does not provide an embedding model; set embedding_model")
+	}
+	return embedTexts(ctx, p.client, embeddingsURL(p.endpoint()), p.config.APIKey, "DeepSeek",
+		p.EmbeddingInfo(), p.config.EmbeddingDimensions > 0, texts)
+}
+
+func (p *DeepSeekProvider) EmbeddingInfo() types.EmbeddingInfo {
+	return embeddingInfo(p.config.EmbeddingModel, p.config.EmbeddingDimensions)
 }
 
 func (p *DeepSeekProvider) GetName() string {
@@ -95,13 +194,28 @@ func (p *DeepSeekProvider) GetModel() string {
 	return p.config.Model
 }
 
-func (p *DeepSeekProvider) SupportsFeature(feature string) bool {
+func (p *DeepSeekProvider) SupportsFeature(feature types.Capability) bool {
 	switch feature {
-	case "long_context":
+	case types.CapLongContext:
 		return true
-	case "multimodal":
+	case types.CapMultimodal:
 		return p.config.Model == "deepseek-vision"
+	case types.CapTools:
+		// deepseek-reasoner 不支持函数调用
+		return p.config.Model != "deepseek-reasoner"
+	case types.CapReasoning:
+		return p.config.Model == "deepseek-reasoner"
+	case types.CapStructuredOutput:
+		return p.formatMode() != formatNone
 	default:
 		return false
 	}
 }
+
+// formatMode 返回支持的 response_format，DeepSeek 只提供 JSON 模式且 deepseek-reasoner 不支持
+func (p *DeepSeekProvider) formatMode() formatMode {
+	if p.config.Model == "deepseek-reasoner" {
+		return formatNone
+	}
+	return formatJSONObject
+}
@@ -0,0 +1,112 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// embeddingBatchSize 是单次请求的最大文本数，SiliconFlow 等服务限制为 32
+const embeddingBatchSize = 32
+
+// knownEmbeddingDimensions 是常用向量模型的默认维度，键为小写的模型 ID
+var knownEmbeddingDimensions = map[string]int{
+	"text-embedding-3-small":               1536,
+	"text-embedding-3-large":               3072,
+	"text-embedding-ada-002":               1536,
+	"baai/bge-m3":                          1024,
+	"baai/bge-large-zh-v1.5":               1024,
+	"baai/bge-large-en-v1.5":               1024,
+	"netease-youdao/bce-embedding-base_v1": 768,
+}
+
+// embeddingInfo 返回向量模型的信息，配置了维度时以配置为准
+func embeddingInfo(model string, dimensions int) types.EmbeddingInfo {
+	if dimensions == 0 {
+		dimensions = knownEmbeddingDimensions[strings.ToLower(model)]
+	}
+	return types.EmbeddingInfo{Model: model, Dimensions: dimensions}
+}
+
+// embeddingsURL 由 chat completions 地址推导出同一服务的 embeddings 地址
+func embeddingsURL(chatURL string) string {
+	base := strings.TrimSuffix(chatURL, "/")
+	base = strings.TrimSuffix(base, "/chat/completions")
+	return base + "/embeddings"
+}
+
+// embedTexts 调用 OpenAI 兼容的 POST /embeddings 接口，按批次请求并按 index 还原顺序。
+// info 中的维度来自配置时随请求发送，所有向量的维度须与之一致
+func embedTexts(ctx context.Context, client *http.Client, url, apiKey, name string,
+	info types.EmbeddingInfo, configured bool, texts []string) ([][]float32, error) {
+	
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		batch := texts[start:min(start+embeddingBatchSize, len(texts))]
+	
+		requestBody := map[string]interface{}{
+			"model":           info.Model,
+			"input":           batch,
+			"encoding_format": "float",
+		}
+		if configured {
+			requestBody["dimensions"] = info.Dimensions
+		}
+	
+		jsonData, err := json.Marshal(requestBody)
+		if err != nil {
+			return nil, err
+		}
+	
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+	
+		if resp.StatusCode != http.StatusOK {
+			apiErr := newAPIError(name, resp)
+			resp.Body.Close()
+			return nil, apiErr
+		}
+	
+		var response struct {
+			Data []struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			} `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s returned invalid embeddings: %v", name, err)
+		}
+		if len(response.Data) != len(batch) {
+			return nil, fmt.Errorf("%s returned %d embeddings for %d inputs", name, len(response.Data), len(batch))
+		}
+	
+		ordered := make([][]float32, len(batch))
+		for _, d := range response.Data {
+			if d.Index < 0 || d.Index >= len(batch) || ordered[d.Index] != nil {
+				return nil, fmt.Errorf("%s returned an invalid embedding index %d", name, d.Index)
+			}
+			if info.Dimensions > 0 && len(d.Embedding) != info.Dimensions {
+				return nil, fmt.Errorf("%s returned %d-dimensional embeddings, expected %d", name, len(d.Embedding), info.Dimensions)
+			}
+			ordered[d.Index] = d.Embedding
+		}
+		vectors = append(vectors, ordered...)
+	}
+	return vectors, nil
+}
@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+func TestEmbedTexts(t *testing.T) {
+	// 按相反顺序返回向量，向量的值为输入文本的序号
+	var batches []int
+	var dimensions []interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input      []string    `json:"input"`
+			Dimensions interface{} `json:"dimensions"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		batches = append(batches, len(body.Input))
+		dimensions = append(dimensions, body.Dimensions)
+		
+		var data []string
+		for i := len(body.Input) - 1; i >= 0; i-- {
+			n, _ := strconv.Atoi(body.Input[i])
+			data = append(data, fmt.Sprintf(`{"index":%d,"embedding":[%d,0]}`, i, n))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(data, ","))
+	}))
+	defer srv.Close()
+	
+	texts := make([]string, embeddingBatchSize*2+5)
+	for i := range texts {
+		texts[i] = strconv.Itoa(i)
+	}
+	info := types.EmbeddingInfo{Model: "text-embedding-3-small", Dimensions: 2}
+	vectors, err := embedTexts(context.Background(), srv.Client(), srv.URL+"/embeddings", stubAPIKey, "OpenAI", info, true, texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	if fmt.Sprint(batches) != fmt.Sprint([]int{embeddingBatchSize, embeddingBatchSize, 5}) {
+		t.Errorf("batch sizes = %v", batches)
+	}
+	if dimensions[0] != float64(2) {
+		t.Errorf("dimensions = %v, want 2", dimensions[0])
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("got %d vectors for %d texts", len(vectors), len(texts))
+	}
+	for i, v := range vectors {
+		if v[0] != float32(i) {
+			t.Fatalf("vector %d belongs to text %v", i, v[0])
+		}
+	}
+}
+
+func TestEmbedTextsInvalidResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"missing embedding", `[{"index":0,"embedding":[1,0]}]`, "1 embeddings for 2 inputs"},
+		{"duplicate index", `[{"index":0,"embedding":[1,0]},{"index":0,"embedding":[1,0]}]`, "invalid embedding index 0"},
+		{"index out of range", `[{"index":0,"embedding":[1,0]},{"index":2,"embedding":[1,0]}]`, "invalid embedding index 2"},
+		{"wrong dimensions", `[{"index":0,"embedding":[1,0,0]},{"index":1,"embedding":[1,0,0]}]`, "3-dimensional embeddings, expected 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"data":%s}`, tt.data)
+			}))
+			defer srv.Close()
+			
+			info := types.EmbeddingInfo{Model: "m", Dimensions: 2}
+			_, err := embedTexts(context.Background(), srv.Client(), srv.URL, stubAPIKey, "OpenAI", info, false, []string{"a", "b"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEmbeddingsURL(t *testing.T) {
+	tests := []struct{ chat, want string }{
+		{"https://api.openai.com/v1/chat/completions", "https://api.openai.com/v1/embeddings"},
+		{"https://api.siliconflow.cn/v1/chat/completions/", "https://api.siliconflow.cn/v1/embeddings"},
+		{"https://gateway.example.com/v1", "https://gateway.example.com/v1/embeddings"},
+	}
+	for _, tt := range tests {
+		if got := embeddingsURL(tt.chat); got != tt.want {
+			t.Errorf("embeddingsURL(%q) = %q, want %q", tt.chat, got, tt.want)
+		}
+	}
+}
@@ -8,20 +8,16 @@ import (
 
 // CreateProvider 根据配置创建供应商实例
 func CreateProvider(config types.APIConfig) (types.AIProvider, error) {
-	switch config.Provider {
-	case "openai":
-		return openai.NewProvider(config)
-	case "azure":
-		return azure.NewProvider(config)
-	case "deepseek":
-		return deepseek.NewProvider(config)
-	case "bailian":
-		return bailian.NewProvider(config)
-	case "siliconflow":
-		return siliconflow.NewProvider(config)
-	case "custom":
-		return custom.NewProvider(config)
-	default:
+	reg, ok := Lookup(config.Provider)
+	if !ok {
 		return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
 	}
+	
+	if reg.Validate != nil {
+		if err := reg.Validate(config); err != nil {
+			return nil, err
+		}
+	}
+	
+	return reg.New(config)
 }
@@ -0,0 +1,255 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// FallbackMember 是回退链中的一个配置及其供应商实例
+type FallbackMember struct {
+	Profile  string
+	Provider types.AIProvider
+}
+
+// FallbackEvent 描述一次从失败的供应商切换到下一个供应商
+type FallbackEvent struct {
+	From string
+	To   string
+	Err  error
+}
+
+type fallbackNotifierKey struct{}
+
+// WithFallbackNotifier 返回携带回退回调的上下文，切换供应商前会调用 fn
+func WithFallbackNotifier(ctx context.Context, fn func(FallbackEvent)) context.Context {
+	return context.WithValue(ctx, fallbackNotifierKey{}, fn)
+}
+
+func notifyFallback(ctx context.Context, event FallbackEvent) {
+	if fn, ok := ctx.Value(fallbackNotifierKey{}).(func(FallbackEvent)); ok && fn != nil {
+		fn(event)
+	}
+}
+
+// FallbackProvider 按顺序尝试回退链中的供应商，
+// 当前供应商因限流、服务端错误、认证失败或网络问题失败时切换到下一个
+type FallbackProvider struct {
+	members []FallbackMember
+	
+	mu       sync.Mutex
+	answered int // 最近一次给出回复的成员下标
+}
+
+func NewFallbackProvider(members []FallbackMember) (*FallbackProvider, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("fallback chain requires at least one provider")
+	}
+	
+	return &FallbackProvider{
+		members: members,
+	}, nil
+}
+
+// try 依次调用 fn，直到成功或遇到不应回退的错误
+func (p *FallbackProvider) try(ctx context.Context, eligible func(FallbackMember) bool,
+	fn func(FallbackMember) (bool, error)) error {
+	
+	var lastErr error
+	var lastName string
+	for i, member := range p.members {
+		if eligible != nil && !eligible(member) {
+			continue
+		}
+		if lastErr != nil {
+			notifyFallback(ctx, FallbackEvent{From: lastName, To: member.Profile, Err: lastErr})
+		}
+		
+		retryable, err := fn(member)
+		if err == nil {
+			p.mu.Lock()
+			p.answered = i
+			p.mu.Unlock()
+			return nil
+		}
+		if !retryable || !ShouldFallback(err) {
+			return err
+		}
+		lastErr, lastName = err, member.Profile
+	}
+	
+	if lastErr == nil {
+		return fmt.Errorf("no provider in the fallback chain supports this request")
+	}
+	return lastErr
+}
+
+func (p *FallbackProvider) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	var response types.Response
+	err := p.try(ctx, p.acceptsImages(messages), func(m FallbackMember) (bool, error) {
+		var err error
+		response, err = m.Provider.SendRequest(ctx, messages)
+		return true, err
+	})
+	return response, err
+}
+
+func (p *FallbackProvider) StreamRequest(ctx context.Context, messages []types.Message, onChunk func(string)) (types.Response, error) {
+	var response types.Response
+	err := p.try(ctx, p.acceptsImages(messages), func(m FallbackMember) (bool, error) {
+		// 已经输出过片段时不再切换，避免拼接两个供应商的回复
+		delivered := false
+		var err error
+		response, err = Stream(ctx, m.Provider, messages, func(chunk string) {
+			delivered = true
+			if onChunk != nil {
+				onChunk(chunk)
+			}
+		})
+		return !delivered, err
+	})
+	return response, err
+}
+
+func (p *FallbackProvider) SendWithTools(ctx context.Context, messages []types.Message, tools []types.Tool) (types.Response, error) {
+	var reply types.Response
+	err := p.try(ctx, p.supportsTools(messages), func(m FallbackMember) (bool, error) {
+		var err error
+		reply, err = m.Provider.(types.ToolCallingProvider).SendWithTools(ctx, messages, tools)
+		return true, err
+	})
+	return reply, err
+}
+
+func (p *FallbackProvider) StreamWithTools(ctx context.Context, messages []types.Message, tools []types.Tool,
+	onChunk func(string)) (types.Response, error) {
+	
+	var reply types.Response
+	err := p.try(ctx, p.supportsTools(messages), func(m FallbackMember) (bool, error) {
+		// 与 StreamRequest 相同，已经输出过片段时不再切换
+		delivered := false
+		var err error
+		reply, err = StreamWithTools(ctx, m.Provider.(types.ToolCallingProvider), messages, tools, func(chunk string) {
+			delivered = true
+			if onChunk != nil {
+				onChunk(chunk)
+			}
+		})
+		return !delivered, err
+	})
+	return reply, err
+}
+
+// supportsTools 只选择支持工具调用的成员，对话附带图片时还须支持图片输入
+func (p *FallbackProvider) supportsTools(messages []types.Message) func(FallbackMember) bool {
+	images := p.acceptsImages(messages)
+	return func(m FallbackMember) bool {
+		_, ok := m.Provider.(types.ToolCallingProvider)
+		return ok && m.Provider.SupportsFeature(types.CapTools) && (images == nil || images(m))
+	}
+}
+
+// SendStructured 由支持结构化输出的成员按 format 回复，其他成员以普通请求发送，
+// 回复是否为 JSON 对象取决于提示词，调用方可通过 Answered 区分
+func (p *FallbackProvider) SendStructured(ctx context.Context, messages []types.Message, format types.ResponseFormat,
+	onChunk func(string)) (types.Response, error) {
+	
+	var response types.Response
+	err := p.try(ctx, p.acceptsImages(messages), func(m FallbackMember) (bool, error) {
+		// 与 StreamRequest 相同，已经输出过片段时不再切换
+		delivered := false
+		var chunk func(string)
+		if onChunk != nil {
+			chunk = func(s string) {
+				delivered = true
+				onChunk(s)
+			}
+		}
+		
+		var err error
+		switch {
+		case SupportsStructuredOutput(m.Provider):
+			response, err = StreamStructured(ctx, m.Provider, messages, format, chunk)
+		case chunk != nil:
+			response, err = Stream(ctx, m.Provider, messages, chunk)
+		default:
+			response, err = m.Provider.SendRequest(ctx, messages)
+		}
+		return !delivered, err
+	})
+	return response, err
+}
+
+// acceptsImages 在对话附带图片时只选择支持图片输入的成员。
+// 没有成员支持时不限制，由主供应商给出拒绝的原因
+func (p *FallbackProvider) acceptsImages(messages []types.Message) func(FallbackMember) bool {
+	if !hasImages(messages) {
+		return nil
+	}
+	eligible := func(m FallbackMember) bool {
+		return m.Provider.SupportsFeature(types.CapMultimodal)
+	}
+	for _, member := range p.members {
+		if eligible(member) {
+			return eligible
+		}
+	}
+	return nil
+}
+
+// ListModels 列出主供应商的模型
+func (p *FallbackProvider) ListModels(ctx context.Context) ([]types.ModelInfo, error) {
+	lister, ok := p.members[0].Provider.(types.ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support listing models", p.GetName())
+	}
+	return lister.ListModels(ctx)
+}
+
+// Embed 使用主供应商的向量模型，向量须来自同一模型，因此不回退
+func (p *FallbackProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embedder, ok := p.members[0].Provider.(types.Embedder)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support embeddings", p.GetName())
+	}
+	return embedder.Embed(ctx, texts)
+}
+
+// EmbeddingInfo 返回主供应商的向量模型，不支持时为空
+func (p *FallbackProvider) EmbeddingInfo() types.EmbeddingInfo {
+	if embedder, ok := p.members[0].Provider.(types.Embedder); ok {
+		return embedder.EmbeddingInfo()
+	}
+	return types.EmbeddingInfo{}
+}
+
+// Answered 返回最近一次给出回复的配置名及供应商
+func (p *FallbackProvider) Answered() (string, types.AIProvider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	member := p.members[p.answered]
+	return member.Profile, member.Provider
+}
+
+// IsPrimary 报告最近一次回复是否来自主供应商
+func (p *FallbackProvider) IsPrimary() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	return p.answered == 0
+}
+
+func (p *FallbackProvider) GetName() string {
+	return p.members[0].Provider.GetName()
+}
+
+func (p *FallbackProvider) GetModel() string {
+	return p.members[0].Provider.GetModel()
+}
+
+func (p *FallbackProvider) SupportsFeature(feature types.Capability) bool {
+	return p.members[0].Provider.SupportsFeature(feature)
+}
@@ -0,0 +1,94 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// plainProvider 只实现 AIProvider，不支持结构化输出
+type plainProvider struct {
+	calls int
+}
+
+func (p *plainProvider) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	p.calls++
+	return types.Response{Content: `{"message":"plain","operations":[]}`}, nil
+}
+
+func (p *plainProvider) GetName() string                       { return "plain" }
+func (p *plainProvider) GetModel() string                      { return "plain-model" }
+func (p *plainProvider) SupportsFeature(types.Capability) bool { return false }
+
+func TestSendStructured(t *testing.T) {
+	format := types.ResponseFormat{Name: "reply", Schema: map[string]interface{}{"type": "object"}}
+	
+	tests := []struct {
+		name       string
+		status     int    // OpenAI 接口返回的状态码
+		wantFormat string // 请求体中 response_format 的 type
+		wantPlain  int    // 普通供应商收到的请求数
+		wantAnswer string // 给出回复的配置
+	}{
+		{"primary honors the format", http.StatusOK, "json_schema", 0, "primary"},
+		{"fallback without structured output", http.StatusServiceUnavailable, "json_schema", 1, "backup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFormat string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					ResponseFormat struct {
+						Type string `json:"type"`
+					} `json:"response_format"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				gotFormat = body.ResponseFormat.Type
+				if tt.status != http.StatusOK {
+					http.Error(w, "unavailable", tt.status)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"model":"gpt-4o","choices":[{"message":{"content":"{}"},"finish_reason":"stop"}]}`))
+			}))
+			defer srv.Close()
+	
+			primary, err := NewOpenAIProvider(types.APIConfig{APIKey: "sk-test", APIBase: srv.URL, Model: "gpt-4o", MaxRetries: -1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			backup := &plainProvider{}
+			chain, err := NewFallbackProvider([]FallbackMember{{"primary", primary}, {"backup", backup}})
+			if err != nil {
+				t.Fatal(err)
+			}
+	
+			if !SupportsStructuredOutput(chain) || SupportsStructuredOutput(backup) {
+				t.Fatal("structured output support should follow the primary provider")
+			}
+			if _, err := StreamStructured(context.Background(), chain, userMessage("hi"), format, nil); err != nil {
+				t.Fatal(err)
+			}
+			if gotFormat != tt.wantFormat {
+				t.Errorf("response_format = %q, want %q", gotFormat, tt.wantFormat)
+			}
+			if backup.calls != tt.wantPlain {
+				t.Errorf("plain provider called %d times, want %d", backup.calls, tt.wantPlain)
+			}
+			if profile, _ := chain.Answered(); profile != tt.wantAnswer {
+				t.Errorf("answered by %q, want %q", profile, tt.wantAnswer)
+			}
+		})
+	}
+	
+	// 不支持结构化输出的供应商报错，而不是忽略格式
+	_, err := StreamStructured(context.Background(), &plainProvider{}, userMessage("hi"), format, nil)
+	if err == nil || !strings.Contains(err.Error(), "does not support structured output") {
+		t.Errorf("StreamStructured on plain provider = %v", err)
+	}
+}
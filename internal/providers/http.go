@@ -0,0 +1,262 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+const (
+	// defaultRequestTimeout 是未配置 timeout 时等待响应的超时
+	defaultRequestTimeout = 120 * time.Second
+	
+	// dialTimeout 是建立 TCP 连接的超时
+	dialTimeout = 30 * time.Second
+)
+
+// newHTTPClient 按配置的超时、网络设置与重试策略创建供应商使用的 HTTP 客户端。
+// 启用了响应缓存时最先查找缓存；每次尝试（包括重试）都经过限流，启用了录制/回放时在最底层接入磁带。
+//
+// 超时只限制等待响应头与读取响应体时两次收到数据之间的间隔，不限制整个请求：
+// 流式回复与推理模型可能持续数分钟，重试退避与限流等待也不应计入
+func newHTTPClient(config types.APIConfig) (*http.Client, error) {
+	timeout := defaultRequestTimeout
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+	
+	base, err := newTransport(config.Transport)
+	if err != nil {
+		return nil, err
+	}
+	base.DialContext = (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	base.ResponseHeaderTimeout = timeout
+	
+	var transport http.RoundTripper = &idleTimeoutTransport{base: base, timeout: timeout}
+	c, err := activeCassette()
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		transport = newCassetteTransport(transport, c, config)
+	}
+	if config.RPM > 0 || config.TPM > 0 {
+		transport = &rateLimitTransport{base: transport, limiter: sharedRateLimiter(config)}
+	}
+	
+	transport = newRetryTransport(transport, config)
+	if rc := activeResponseCache(); rc != nil {
+		transport = &cacheTransport{base: transport, cache: rc, provider: config.Provider}
+	}
+	
+	return &http.Client{
+		Transport: transport,
+	}, nil
+}
+
+// idleTimeoutTransport 为响应体设置读取超时：单次读取超过 timeout 仍未收到数据时中止响应
+type idleTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &idleTimeoutBody{ReadCloser: resp.Body, timeout: t.timeout}
+	return resp, nil
+}
+
+// idleTimeoutBody 只在 Read 阻塞期间计时，调用方处理数据的时间不计入
+type idleTimeoutBody struct {
+	io.ReadCloser
+	timeout time.Duration
+	
+	mu      sync.Mutex
+	timer   *time.Timer
+	expired bool
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	if b.expired {
+		b.mu.Unlock()
+		return 0, errIdleTimeout{b.timeout}
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.timeout, b.expire)
+	} else {
+		b.timer.Reset(b.timeout)
+	}
+	b.mu.Unlock()
+	
+	n, err := b.ReadCloser.Read(p)
+	
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timer.Stop()
+	if err != nil && b.expired {
+		return n, errIdleTimeout{b.timeout}
+	}
+	return n, err
+}
+
+// expire 关闭底层响应体，使阻塞的 Read 返回
+func (b *idleTimeoutBody) expire() {
+	b.mu.Lock()
+	b.expired = true
+	b.mu.Unlock()
+	b.ReadCloser.Close()
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.mu.Lock()
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.mu.Unlock()
+	return b.ReadCloser.Close()
+}
+
+// errIdleTimeout 表示响应中途长时间没有数据，按超时处理
+type errIdleTimeout struct {
+	timeout time.Duration
+}
+
+func (e errIdleTimeout) Error() string {
+	return fmt.Sprintf("no data received from the server for %s", e.timeout)
+}
+
+func (e errIdleTimeout) Timeout() bool { return true }
+
+// newTransport 在默认 Transport 的基础上应用代理与 TLS 设置
+func newTransport(config types.TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	
+	switch config.Proxy {
+	case "":
+		// 沿用环境变量中的代理设置
+	case "direct":
+		transport.Proxy = nil
+	default:
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL: %s", config.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	
+	if config.CAFile == "" && config.ClientCert == "" && config.TLSMinVersion == "" && !config.InsecureSkipVerify {
+		return transport, nil
+	}
+	
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	
+	if config.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %v", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" || config.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	
+	if config.TLSMinVersion != "" {
+		version, ok := map[string]uint16{
+			"1.0": tls.VersionTLS10,
+			"1.1": tls.VersionTLS11,
+			"1.2": tls.VersionTLS12,
+			"1.3": tls.VersionTLS13,
+		}[config.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported tls_min_version: %s", config.TLSMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// APIError 表示供应商返回了非成功的 HTTP 响应
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error: %s - %s", e.Provider, e.Status, e.Body)
+}
+
+// newAPIError 读取响应体并构造 APIError
+func newAPIError(provider string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       string(body),
+	}
+}
+
+// ShouldFallback 判断错误是否应切换到备用供应商：
+// 限流、服务端错误、认证失败以及网络错误会切换，请求本身有误或被用户取消则不会
+func ShouldFallback(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized,
+			apiErr.StatusCode == http.StatusForbidden,
+			apiErr.StatusCode == http.StatusPaymentRequired,
+			apiErr.StatusCode == http.StatusRequestTimeout,
+			apiErr.StatusCode == http.StatusTooManyRequests,
+			apiErr.StatusCode >= 500:
+			return true
+		default:
+			return false
+		}
+	}
+
+	// 超时与连接失败等传输层错误。*url.Error 都实现了 Timeout()，须确认确实是超时；
+	// 证书错误、回放缺失与客户端限流等本地错误换用其他配置也无济于事
+	var netErr interface{ Timeout() bool }
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) || isUnsentError(err)
+}
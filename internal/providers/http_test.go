@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIdleTimeoutTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pause, _ := time.ParseDuration(r.URL.Query().Get("pause"))
+		for i := 0; i < 3; i++ {
+			io.WriteString(w, "data: chunk\n\n")
+			w.(http.Flusher).Flush()
+			time.Sleep(pause)
+		}
+	}))
+	defer srv.Close()
+	
+	client := &http.Client{Transport: &idleTimeoutTransport{base: http.DefaultTransport, timeout: 200 * time.Millisecond}}
+	
+	tests := []struct {
+		pause   string
+		timeout bool
+	}{
+		// 总耗时超过 timeout，但每段数据的间隔都在 timeout 内
+		{"100ms", false},
+		{"500ms", true},
+	}
+	for _, tt := range tests {
+		resp, err := client.Get(srv.URL + "?pause=" + tt.pause)
+		if err != nil {
+			t.Fatalf("pause %s: %v", tt.pause, err)
+		}
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		
+		var idle errIdleTimeout
+		if got := errors.As(err, &idle); got != tt.timeout {
+			t.Errorf("pause %s: err = %v, want timeout %v", tt.pause, err, tt.timeout)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// hasImages 判断对话中是否有附带图片的消息
+func hasImages(messages []types.Message) bool {
+	for _, msg := range messages {
+		if len(msg.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImages 在对话附带图片而供应商不支持图片输入时返回错误，避免图片被静默丢弃
+func checkImages(provider types.AIProvider, messages []types.Message) error {
+	if hasImages(messages) && !provider.SupportsFeature(types.CapMultimodal) {
+		return fmt.Errorf("%s (%s) does not accept image input", provider.GetName(), provider.GetModel())
+	}
+	return nil
+}
+
+// flattenMessages 将对话渲染为单段文本，供只接受 prompt 字符串的接口使用
+func flattenMessages(messages []types.Message) string {
+	if len(messages) == 1 && messages[0].Role == types.RoleUser {
+		return messages[0].Content
+	}
+	
+	var sb strings.Builder
+	for i, msg := range messages {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "[%s]\n%s", msg.Role, msg.Content)
+	}
+	return sb.String()
+}
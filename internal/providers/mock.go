@@ -0,0 +1,228 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+	
+	"github.com/yantianyv/AkashaTerminal/internal/fileutil"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+func init() {
+	Register(Registration{
+		Name:        "mock",
+		Description: "脚本化的模拟供应商，用于离线开发与演示",
+		Validate:    validateMockConfig,
+		Fields:      []string{"script", "model"},
+		New: func(config types.APIConfig) (types.AIProvider, error) {
+			return NewMockProvider(config)
+		},
+	})
+}
+
+// mockRule 是脚本中的一条回复规则。
+// Turn 与 Match 都为空的规则作为默认回复；Content 与 JSON 二选一，JSON 会原样作为回复文本
+type mockRule struct {
+	Turn         int             `json:"turn,omitempty"`           // 匹配第几轮请求，从 1 开始
+	Match        string          `json:"match,omitempty"`          // 匹配最后一条用户消息的正则
+	Content      string          `json:"content,omitempty"`        // 回复文本，可以是格式错误的 JSON
+	JSON         json.RawMessage `json:"json,omitempty"`           // 回复的 JSON，如操作指令
+	DelayMs      int             `json:"delay_ms,omitempty"`       // 回复前等待的时间
+	ChunkDelayMs int             `json:"chunk_delay_ms,omitempty"` // 流式输出时每个片段之间的间隔
+	Error        string          `json:"error,omitempty"`          // 返回错误而不是回复
+	Status       int             `json:"status,omitempty"`         // 配合 Error 模拟 HTTP 状态码，如 429
+	Usage        *types.Usage    `json:"usage,omitempty"`          // 报告的用量，省略时按字符数估算
+	
+	pattern *regexp.Regexp
+}
+
+// mockScript 是 mock 供应商的脚本文件格式
+type mockScript struct {
+	Responses []*mockRule `json:"responses"`
+}
+
+// MockProvider 按脚本返回预设的回复，不访问网络。
+// 每次请求先找轮次匹配的规则，再按顺序找正则匹配的规则，最后使用默认规则
+type MockProvider struct {
+	config types.APIConfig
+	script mockScript
+	
+	mu   sync.Mutex
+	turn int
+}
+
+// validateMockConfig 检查 mock 配置的必填字段
+func validateMockConfig(config types.APIConfig) error {
+	if config.Script == "" {
+		return fmt.Errorf("mock provider requires a script file")
+	}
+	return nil
+}
+
+func NewMockProvider(config types.APIConfig) (*MockProvider, error) {
+	if err := validateMockConfig(config); err != nil {
+		return nil, err
+	}
+	
+	data, err := os.ReadFile(config.Script)
+	if err != nil {
+		return nil, fmt.Errorf("read mock script: %v", err)
+	}
+	
+	var script mockScript
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("parse mock script %s: %v", config.Script, err)
+	}
+	for i, rule := range script.Responses {
+		if rule.Match == "" {
+			continue
+		}
+		rule.pattern, err = regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("mock script rule %d: %v", i+1, err)
+		}
+	}
+	
+	return &MockProvider{
+		config: config,
+		script: script,
+	}, nil
+}
+
+// next 推进轮次并选出本轮使用的规则
+func (p *MockProvider) next(messages []types.Message) (*mockRule, error) {
+	if err := checkImages(p, messages); err != nil {
+		return nil, err
+	}
+	
+	p.mu.Lock()
+	p.turn++
+	turn := p.turn
+	p.mu.Unlock()
+	
+	prompt := ""
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == types.RoleUser {
+			prompt = messages[i].Content
+			break
+		}
+	}
+	
+	for _, rule := range p.script.Responses {
+		if rule.Turn == turn {
+			return rule, nil
+		}
+	}
+	
+	var fallback *mockRule
+	for _, rule := range p.script.Responses {
+		switch {
+		case rule.Turn != 0:
+			// 只按轮次匹配
+		case rule.pattern != nil:
+			if rule.pattern.MatchString(prompt) {
+				return rule, nil
+			}
+		case fallback == nil:
+			fallback = rule
+		}
+	}
+	
+	if fallback == nil {
+		return nil, fmt.Errorf("mock script has no response for turn %d", turn)
+	}
+	return fallback, nil
+}
+
+// respond 等待规则要求的延迟后给出回复或错误
+func (p *MockProvider) respond(ctx context.Context, rule *mockRule, messages []types.Message) (types.Response, error) {
+	if err := fileutil.SleepContext(ctx, time.Duration(rule.DelayMs)*time.Millisecond); err != nil {
+		return types.Response{}, err
+	}
+	
+	if rule.Error != "" || rule.Status != 0 {
+		if rule.Status != 0 {
+			return types.Response{}, &APIError{
+				Provider:   p.GetName(),
+				StatusCode: rule.Status,
+				Status:     fmt.Sprintf("%d %s", rule.Status, http.StatusText(rule.Status)),
+				Body:       rule.Error,
+			}
+		}
+		return types.Response{}, fmt.Errorf("%s", rule.Error)
+	}
+	
+	content := rule.Content
+	if len(rule.JSON) > 0 {
+		content = string(rule.JSON)
+	}
+	
+	response := types.Response{
+		Content:      content,
+		FinishReason: "stop",
+		Model:        p.GetModel(),
+	}
+	if rule.Usage != nil {
+		response.Usage = *rule.Usage
+		return response, nil
+	}
+	// 与其他供应商缺少用量时相同，按字符估算并标记为估算值
+	return withEstimatedUsage(response, messages), nil
+}
+
+func (p *MockProvider) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	rule, err := p.next(messages)
+	if err != nil {
+		return types.Response{}, err
+	}
+	return p.respond(ctx, rule, messages)
+}
+
+// StreamRequest 将回复按几个字符一段依次交给 onChunk，以演示流式输出
+func (p *MockProvider) StreamRequest(ctx context.Context, messages []types.Message, onChunk func(string)) (types.Response, error) {
+	rule, err := p.next(messages)
+	if err != nil {
+		return types.Response{}, err
+	}
+	
+	response, err := p.respond(ctx, rule, messages)
+	if err != nil {
+		return types.Response{}, err
+	}
+	
+	const chunkRunes = 8
+	runes := []rune(response.Content)
+	for start := 0; start < len(runes); start += chunkRunes {
+		if start > 0 {
+			if err := fileutil.SleepContext(ctx, time.Duration(rule.ChunkDelayMs)*time.Millisecond); err != nil {
+				return types.Response{}, err
+			}
+		}
+		end := min(start+chunkRunes, len(runes))
+		if onChunk != nil {
+			onChunk(string(runes[start:end]))
+		}
+	}
+	return response, nil
+}
+
+func (p *MockProvider) GetName() string {
+	return "Mock"
+}
+
+func (p *MockProvider) GetModel() string {
+	if p.config.Model != "" {
+		return p.config.Model
+	}
+	return "mock"
+}
+
+func (p *MockProvider) SupportsFeature(feature types.Capability) bool {
+	return false
+}
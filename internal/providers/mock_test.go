@@ -0,0 +1,168 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+func newTestMockProvider(t *testing.T, script string) (*MockProvider, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return NewMockProvider(types.APIConfig{Provider: "mock", Script: path})
+}
+
+func TestMockRuleSelection(t *testing.T) {
+	p, err := newTestMockProvider(t, `{"responses": [
+		{"turn": 2, "content": "second"},
+		{"match": "(?i)^hello", "content": "greeting"},
+		{"content": "default"},
+		{"match": "bye", "content": "farewell"},
+		{"content": "unused default"}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	// 依次发送，轮次从 1 开始计数
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"Hello there", "greeting"},
+		{"hello again", "second"}, // 轮次优先于正则
+		{"bye", "farewell"},       // 正则规则不受默认规则位置影响
+		{"something else", "default"},
+	}
+	for i, tt := range tests {
+		response, err := p.SendRequest(context.Background(), userMessage(tt.prompt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Content != tt.want {
+			t.Errorf("turn %d: reply to %q = %q, want %q", i+1, tt.prompt, response.Content, tt.want)
+		}
+	}
+	
+	p, err = newTestMockProvider(t, `{"responses": [{"match": "only", "json": {"message": "ok"}}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response, err := p.SendRequest(context.Background(), userMessage("only this")); err != nil || response.Content != `{"message": "ok"}` {
+		t.Errorf("json rule = %q, %v", response.Content, err)
+	}
+	if _, err := p.SendRequest(context.Background(), userMessage("other")); err == nil || !strings.Contains(err.Error(), "no response for turn 2") {
+		t.Errorf("missing default error = %v", err)
+	}
+	
+	if _, err := newTestMockProvider(t, `{"responses": [{"match": "("}]}`); err == nil || !strings.Contains(err.Error(), "rule 1") {
+		t.Errorf("invalid pattern error = %v", err)
+	}
+}
+
+func TestMockErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		status  int // 0 表示不是 APIError
+		message string
+	}{
+		{"plain error", `{"error": "boom"}`, 0, "boom"},
+		{"rate limited", `{"error": "slow down", "status": 429}`, 429, "slow down"},
+		{"status without message", `{"status": 503}`, 503, "503"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newTestMockProvider(t, `{"responses": [`+tt.rule+`]}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = p.SendRequest(context.Background(), userMessage("hi"))
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Fatalf("error = %v, want %q", err, tt.message)
+			}
+			var apiErr *APIError
+			if errors.As(err, &apiErr) != (tt.status != 0) || (apiErr != nil && apiErr.StatusCode != tt.status) {
+				t.Errorf("error = %#v, want status %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestMockDelay(t *testing.T) {
+	p, err := newTestMockProvider(t, `{"responses": [{"content": "late", "delay_ms": 50}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	start := time.Now()
+	if _, err := p.SendRequest(context.Background(), userMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("reply after %v, want at least 50ms", elapsed)
+	}
+	
+	// 等待期间取消请求立即返回
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.SendRequest(ctx, userMessage("hi")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("canceled delay error = %v", err)
+	}
+}
+
+func TestMockStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		content string
+		chunks  []string
+		usage   types.Usage
+	}{
+		{
+			name:    "chunks of eight runes",
+			rule:    `{"content": "你好，这是一个模拟的流式回复。", "chunk_delay_ms": 1}`,
+			content: "你好，这是一个模拟的流式回复。",
+			chunks:  []string{"你好，这是一个模", "拟的流式回复。"},
+			// 非 ASCII 字符按 2 个字符估算，与 TokenEstimator 一致
+			usage: types.Usage{PromptTokens: 3, CompletionTokens: 7, TotalTokens: 10, Estimated: true},
+		},
+		{
+			name:    "reported usage",
+			rule:    `{"content": "ok", "usage": {"prompt_tokens": 12, "completion_tokens": 1, "total_tokens": 13}}`,
+			content: "ok",
+			chunks:  []string{"ok"},
+			usage:   types.Usage{PromptTokens: 12, CompletionTokens: 1, TotalTokens: 13},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newTestMockProvider(t, `{"responses": [`+tt.rule+`]}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var chunks []string
+			response, err := p.StreamRequest(context.Background(), userMessage("请回复一段话"), func(chunk string) {
+				chunks = append(chunks, chunk)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Content != tt.content || strings.Join(chunks, "|") != strings.Join(tt.chunks, "|") {
+				t.Errorf("content = %q in chunks %q, want %q in %q", response.Content, chunks, tt.content, tt.chunks)
+			}
+			if response.Usage != tt.usage {
+				t.Errorf("usage = %+v, want %+v", response.Usage, tt.usage)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// knownModel 是内置的模型参数，按 ID 前缀匹配
+type knownModel struct {
+	prefix        string
+	contextWindow int
+	capabilities  []types.Capability
+}
+
+var (
+	chatCaps       = []types.Capability{types.CapTools}
+	longCaps       = []types.Capability{types.CapLongContext, types.CapTools}
+	multimodalCaps = []types.Capability{types.CapLongContext, types.CapTools, types.CapMultimodal}
+	reasoningCaps  = []types.Capability{types.CapLongContext, types.CapReasoning}
+)
+
+// knownModels 是常用模型的上下文窗口与能力，/v1/models 通常不返回这些信息。
+// 前缀不区分大小写，带组织前缀的 ID（如 deepseek-ai/DeepSeek-V3）同时按斜杠后的部分匹配
+var knownModels = []knownModel{
+	{"gpt-4.1", 1047576, multimodalCaps},
+	{"gpt-4o", 128000, multimodalCaps},
+	{"gpt-4-turbo", 128000, multimodalCaps},
+	{"gpt-4", 8192, chatCaps},
+	{"gpt-3.5-turbo", 16385, chatCaps},
+	{"o1", 200000, multimodalCaps},
+	{"o3", 200000, multimodalCaps},
+	{"o4-mini", 200000, multimodalCaps},
+	{"deepseek-chat", 65536, longCaps},
+	{"deepseek-reasoner", 65536, reasoningCaps},
+	{"deepseek-v3", 65536, longCaps},
+	{"deepseek-r1", 65536, reasoningCaps},
+	{"qwen2.5-vl", 32768, []types.Capability{types.CapMultimodal}},
+	{"qwen2.5", 32768, chatCaps},
+	{"qwen-max", 32768, chatCaps},
+	{"qwen-plus", 131072, longCaps},
+	{"qwen-turbo", 1000000, longCaps},
+	{"qwq", 32768, []types.Capability{types.CapReasoning}},
+	{"glm-4", 128000, longCaps},
+	{"yi-", 16384, []types.Capability{types.CapQuantization}},
+}
+
+// LookupModel 在内置表中查找模型参数，最长前缀优先
+func LookupModel(id string) (types.ModelInfo, bool) {
+	candidates := []string{strings.ToLower(id)}
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		candidates = append(candidates, strings.ToLower(id[i+1:]))
+	}
+	
+	var best *knownModel
+	for i := range knownModels {
+		km := &knownModels[i]
+		for _, c := range candidates {
+			if strings.HasPrefix(c, km.prefix) && (best == nil || len(km.prefix) > len(best.prefix)) {
+				best = km
+			}
+		}
+	}
+	if best == nil {
+		return types.ModelInfo{ID: id}, false
+	}
+	
+	return types.ModelInfo{
+		ID:            id,
+		ContextWindow: best.contextWindow,
+		Capabilities:  best.capabilities,
+	}, true
+}
+
+// modelsURL 由 chat completions 地址推导出同一服务的模型列表地址
+func modelsURL(chatURL string) string {
+	base := strings.TrimSuffix(chatURL, "/")
+	base = strings.TrimSuffix(base, "/chat/completions")
+	return base + "/models"
+}
+
+// listModels 调用 OpenAI 兼容的 GET /models 接口，并以内置表补全上下文窗口与能力。
+// 部分服务（如 OpenRouter、vLLM）会直接返回上下文长度，此时以返回值为准
+func listModels(ctx context.Context, client *http.Client, url, apiKey, name string) ([]types.ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(name, resp)
+	}
+	
+	var response struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextLength int    `json:"context_length"`
+			ContextWindow int    `json:"context_window"`
+			MaxModelLen   int    `json:"max_model_len"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("%s returned an invalid model list: %v", name, err)
+	}
+	
+	models := make([]types.ModelInfo, 0, len(response.Data))
+	for _, m := range response.Data {
+		info, _ := LookupModel(m.ID)
+		for _, window := range []int{m.ContextLength, m.ContextWindow, m.MaxModelLen} {
+			if window > 0 {
+				info.ContextWindow = window
+				break
+			}
+		}
+		models = append(models, info)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].ID < models[j].ID
+	})
+	return models, nil
+}
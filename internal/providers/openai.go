@@ -0,0 +1,242 @@
+package providers
+
+import (
+	"context"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+const (
+	defaultOpenAIURL   = "https://api.openai.com/v1/chat/completions"
+	defaultOpenAIModel = "gpt-4o-mini"
+)
+
+func init() {
+	Register(Registration{
+		Name:        "openai",
+		Description: "OpenAI Chat Completions",
+		Validate:    validateOpenAIConfig,
+		Fields:      []string{"api_key", "api_base", "model", "max_tokens"},
+		New: func(config types.APIConfig) (types.AIProvider, error) {
+			return NewOpenAIProvider(config)
+		},
+	})
+}
+
+type OpenAIProvider struct {
+	config types.APIConfig
+	client *http.Client
+}
+
+// validateOpenAIConfig 检查 openai 配置的必填字段
+func validateOpenAIConfig(config types.APIConfig) error {
+	if config.APIKey == "" {
+		return fmt.Errorf("OpenAI API key is required")
+	}
+	return nil
+}
+
+func NewOpenAIProvider(config types.APIConfig) (*OpenAIProvider, error) {
+	if err := validateOpenAIConfig(config); err != nil {
+		return nil, err
+	}
+	
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &OpenAIProvider{
+		config: config,
+		client: client,
+	}, nil
+}
+
+// endpoint 返回 chat completions 地址，APIBase 可覆盖默认值
+func (p *OpenAIProvider) endpoint() string {
+	if p.config.APIBase != "" {
+		return p.config.APIBase
+	}
+	return defaultOpenAIURL
+}
+
+// newRequest 构造 chat completions 请求
+func (p *OpenAIProvider) newRequest(ctx context.Context, chat chatRequest) (*http.Request, error) {
+	if err := checkImages(p, chat.Messages); err != nil {
+		return nil, err
+	}
+	
+	chat.Params = generationParams(ctx, p.config.GenerationParams)
+	chat.FormatMode = p.formatMode()
+	
+	requestBody := map[string]interface{}{
+		"model": p.GetModel(),
+	}
+	
+	if p.config.MaxTokens > 0 {
+		requestBody["max_tokens"] = p.config.MaxTokens
+	}
+	
+	chat.apply(requestBody)
+	
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	return req, nil
+}
+
+func (p *OpenAIProvider) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	return p.SendWithTools(ctx, messages, nil)
+}
+
+func (p *OpenAIProvider) StreamRequest(ctx context.Context, messages []types.Message, onChunk func(string)) (types.Response, error) {
+	return p.stream(ctx, chatRequest{Messages: messages, Stream: true, StreamUsage: true}, onChunk)
+}
+
+// stream 发送流式请求，每收到一段文本调用一次 onChunk
+func (p *OpenAIProvider) stream(ctx context.Context, chat chatRequest, onChunk func(string)) (types.Response, error) {
+	req, err := p.newRequest(ctx, chat)
+	if err != nil {
+		return types.Response{}, err
+	}
+	
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return types.Response{}, newAPIError("OpenAI", resp)
+	}
+	
+	response, err := readChatStream(resp.Body, onChunk, reasoningHandler(ctx))
+	if err != nil {
+		return types.Response{}, err
+	}
+	if response.FinishReason == "content_filter" {
+		return types.Response{}, fmt.Errorf("OpenAI response was blocked by the content filter")
+	}
+	
+	return withModel(response, p.GetModel()), nil
+}
+
+func (p *OpenAIProvider) SendStructured(ctx context.Context, messages []types.Message, format types.ResponseFormat, onChunk func(string)) (types.Response, error) {
+	if onChunk == nil {
+		return p.send(ctx, chatRequest{Messages: messages, Format: &format})
+	}
+	return p.stream(ctx, chatRequest{Messages: messages, Stream: true, StreamUsage: true, Format: &format}, onChunk)
+}
+
+func (p *OpenAIProvider) StreamWithTools(ctx context.Context, messages []types.Message, tools []types.Tool, onChunk func(string)) (types.Response, error) {
+	return p.stream(ctx, chatRequest{Messages: messages, Tools: tools, Stream: true, StreamUsage: true}, onChunk)
+}
+
+func (p *OpenAIProvider) SendWithTools(ctx context.Context, messages []types.Message, tools []types.Tool) (types.Response, error) {
+	return p.send(ctx, chatRequest{Messages: messages, Tools: tools})
+}
+
+// send 发送一次性请求
+func (p *OpenAIProvider) send(ctx context.Context, chat chatRequest) (types.Response, error) {
+	req, err := p.newRequest(ctx, chat)
+	if err != nil {
+		return types.Response{}, err
+	}
+	
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return types.Response{}, newAPIError("OpenAI", resp)
+	}
+	
+	response, err := decodeChatResponse(resp.Body, "OpenAI")
+	if err != nil {
+		return types.Response{}, err
+	}
+	return withModel(response, p.GetModel()), nil
+}
+
+// ListModels 列出账号可用的模型
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]types.ModelInfo, error) {
+	return listModels(ctx, p.client, modelsURL(p.endpoint()), p.config.APIKey, "OpenAI")
+}
+
+// Embed 使用向量模型将文本转换为向量，默认 text-embedding-3-small
+func (p *OpenAIProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedTexts(ctx, p.client, embeddingsURL(p.endpoint()), p.config.APIKey, "OpenAI",
+		p.EmbeddingInfo(), p.config.EmbeddingDimensions > 0, texts)
+}
+
+func (p *OpenAIProvider) EmbeddingInfo() types.EmbeddingInfo {
+	model := p.config.EmbeddingModel
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	return embeddingInfo(model, p.config.EmbeddingDimensions)
+}
+
+func (p *OpenAIProvider) GetName() string {
+	return "OpenAI"
+}
+
+func (p *OpenAIProvider) GetModel() string {
+	if p.config.Model != "" {
+		return p.config.Model
+	}
+	return defaultOpenAIModel
+}
+
+func (p *OpenAIProvider) SupportsFeature(feature types.Capability) bool {
+	switch feature {
+	case types.CapLongContext, types.CapTools:
+		return true
+	case types.CapMultimodal:
+		info, _ := LookupModel(p.GetModel())
+		return info.Supports(types.CapMultimodal)
+	case types.CapStructuredOutput:
+		return p.formatMode() != formatNone
+	default:
+		return false
+	}
+}
+
+func (p *OpenAIProvider) formatMode() formatMode {
+	return openAIFormatMode(p.GetModel())
+}
+
+// openAIFormatMode 返回 OpenAI 模型支持的 response_format：gpt-4-turbo（含 1106、0125 预览版）与 gpt-3.5-turbo
+// 只支持 JSON 模式，更早的 gpt-4 与 gpt-3.5 不支持，gpt-4o、gpt-4.1、gpt-5 与 o 系列支持 json_schema。
+// 网关转发的其他模型未必支持 json_schema，默认使用兼容性更好的 JSON 模式
+func openAIFormatMode(model string) formatMode {
+	model = strings.ToLower(model)
+	switch {
+	case strings.HasPrefix(model, "gpt-4-turbo"), strings.HasPrefix(model, "gpt-4-1106-preview"),
+		strings.HasPrefix(model, "gpt-4-0125-preview"), strings.HasPrefix(model, "gpt-3.5-turbo"):
+		return formatJSONObject
+	case model == "gpt-4", strings.HasPrefix(model, "gpt-4-"), strings.HasPrefix(model, "gpt-3.5"):
+		return formatNone
+	case strings.HasPrefix(model, "gpt-4o"), strings.HasPrefix(model, "gpt-4.1"), strings.HasPrefix(model, "gpt-5"),
+		strings.HasPrefix(model, "o1"), strings.HasPrefix(model, "o3"), strings.HasPrefix(model, "o4"):
+		return formatJSONSchema
+	default:
+		return formatJSONObject
+	}
+}
@@ -0,0 +1,31 @@
+package providers
+
+import "testing"
+
+func TestOpenAIFormatMode(t *testing.T) {
+	tests := []struct {
+		model string
+		want  formatMode
+	}{
+		{"gpt-4o", formatJSONSchema},
+		{"gpt-4o-mini-2024-07-18", formatJSONSchema},
+		{"GPT-4.1", formatJSONSchema},
+		{"gpt-5-mini", formatJSONSchema},
+		{"o3-mini", formatJSONSchema},
+		{"gpt-4-turbo-2024-04-09", formatJSONObject},
+		{"gpt-4-turbo-preview", formatJSONObject},
+		{"gpt-4-1106-preview", formatJSONObject},
+		{"gpt-4-0125-preview", formatJSONObject},
+		{"gpt-3.5-turbo", formatJSONObject},
+		{"gpt-4", formatNone},
+		{"gpt-4-0613", formatNone},
+		{"gpt-3.5", formatNone},
+		{"llama-3-70b", formatJSONObject}, // 网关转发的未知模型
+		{"", formatJSONObject},
+	}
+	for _, tt := range tests {
+		if got := openAIFormatMode(tt.model); got != tt.want {
+			t.Errorf("openAIFormatMode(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
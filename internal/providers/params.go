@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+type generationParamsKey struct{}
+
+// WithGenerationParams 返回携带会话级生成参数的上下文，
+// 其中已设置的参数覆盖配置中的同名参数
+func WithGenerationParams(ctx context.Context, params types.GenerationParams) context.Context {
+	return context.WithValue(ctx, generationParamsKey{}, params)
+}
+
+// generationParams 合并配置中的生成参数与上下文中的会话覆盖
+func generationParams(ctx context.Context, base types.GenerationParams) types.GenerationParams {
+	if override, ok := ctx.Value(generationParamsKey{}).(types.GenerationParams); ok {
+		return base.Merge(override)
+	}
+	return base
+}
+
+// GenerationParamNames 是 SetGenerationParam 支持的参数名
+var GenerationParamNames = []string{"temperature", "top_p", "stop", "seed", "presence_penalty", "frequency_penalty"}
+
+// SetGenerationParam 按名称设置一个生成参数，value 为空或 default 时清除。
+// stop 接受单个字符串或 JSON 字符串数组
+func SetGenerationParam(params *types.GenerationParams, name, value string) error {
+	reset := value == "" || value == "default"
+	
+	parseFloat := func(lo, hi float64) (*float64, error) {
+		if reset {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", name)
+		}
+		if f < lo || f > hi {
+			return nil, fmt.Errorf("%s must be between %g and %g", name, lo, hi)
+		}
+		return &f, nil
+	}
+	
+	var err error
+	switch name {
+	case "temperature":
+		params.Temperature, err = parseFloat(0, 2)
+	case "top_p":
+		params.TopP, err = parseFloat(0, 1)
+	case "presence_penalty":
+		params.PresencePenalty, err = parseFloat(-2, 2)
+	case "frequency_penalty":
+		params.FrequencyPenalty, err = parseFloat(-2, 2)
+	case "seed":
+		if reset {
+			params.Seed = nil
+			return nil
+		}
+		seed, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return fmt.Errorf("seed must be an integer")
+		}
+		params.Seed = &seed
+	case "stop":
+		if reset {
+			params.Stop = nil
+			return nil
+		}
+		if strings.HasPrefix(value, "[") {
+			var stop []string
+			if err := json.Unmarshal([]byte(value), &stop); err != nil {
+				return fmt.Errorf("stop must be a string or a JSON array of strings")
+			}
+			params.Stop = stop
+		} else {
+			params.Stop = []string{value}
+		}
+	default:
+		return fmt.Errorf("unknown parameter %s (supported: %s)", name, strings.Join(GenerationParamNames, ", "))
+	}
+	return err
+}
+
+// FormatGenerationParams 以 name=value 的形式列出已设置的参数
+func FormatGenerationParams(params types.GenerationParams) string {
+	var parts []string
+	if params.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temperature=%g", *params.Temperature))
+	}
+	if params.TopP != nil {
+		parts = append(parts, fmt.Sprintf("top_p=%g", *params.TopP))
+	}
+	if params.Stop != nil {
+		stop, _ := json.Marshal(params.Stop)
+		parts = append(parts, "stop="+string(stop))
+	}
+	if params.Seed != nil {
+		parts = append(parts, fmt.Sprintf("seed=%d", *params.Seed))
+	}
+	if params.PresencePenalty != nil {
+		parts = append(parts, fmt.Sprintf("presence_penalty=%g", *params.PresencePenalty))
+	}
+	if params.FrequencyPenalty != nil {
+		parts = append(parts, fmt.Sprintf("frequency_penalty=%g", *params.FrequencyPenalty))
+	}
+	return strings.Join(parts, " ")
+}
+
+// applyGenerationParams 将已设置的参数写入 OpenAI 兼容的请求体
+func applyGenerationParams(requestBody map[string]interface{}, params types.GenerationParams) {
+	if params.Temperature != nil {
+		requestBody["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		requestBody["top_p"] = *params.TopP
+	}
+	if len(params.Stop) > 0 {
+		requestBody["stop"] = params.Stop
+	}
+	if params.Seed != nil {
+		requestBody["seed"] = *params.Seed
+	}
+	if params.PresencePenalty != nil {
+		requestBody["presence_penalty"] = *params.PresencePenalty
+	}
+	if params.FrequencyPenalty != nil {
+		requestBody["frequency_penalty"] = *params.FrequencyPenalty
+	}
+}
@@ -0,0 +1,262 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+	
+	"github.com/yantianyv/AkashaTerminal/internal/fileutil"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// rateLimitDir 是限流状态与锁文件所在的目录，本机所有 akasha 进程共用
+var rateLimitDir = func() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "akashaterminal", "ratelimit")
+}
+
+// RateLimitEvent 描述一次因客户端限流而进行的等待
+type RateLimitEvent struct {
+	Wait   time.Duration // 发送前的等待时间
+	Reason string        // 额度不足的限制: rpm 或 tpm
+}
+
+type rateLimitNotifierKey struct{}
+
+// WithRateLimitNotifier 返回携带限流回调的上下文，请求因限流等待前会调用 fn
+func WithRateLimitNotifier(ctx context.Context, fn func(RateLimitEvent)) context.Context {
+	return context.WithValue(ctx, rateLimitNotifierKey{}, fn)
+}
+
+func notifyRateLimit(ctx context.Context, event RateLimitEvent) {
+	if fn, ok := ctx.Value(rateLimitNotifierKey{}).(func(RateLimitEvent)); ok && fn != nil {
+		fn(event)
+	}
+}
+
+// rateBucket 是保存在状态文件中的令牌桶余量
+type rateBucket struct {
+	Requests float64   `json:"requests"`
+	Tokens   float64   `json:"tokens"`
+	Updated  time.Time `json:"updated"`
+}
+
+// rateLimiter 是按 RPM 与 TPM 匀速补充的令牌桶，桶容量为一分钟的额度。
+// 进程内以互斥锁同步，进程间通过锁文件同步，余量保存在状态文件中
+type rateLimiter struct {
+	rpm  int
+	tpm  int
+	path string // 状态文件，锁文件为 path + ".lock"
+	
+	mu sync.Mutex
+}
+
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = make(map[string]*rateLimiter)
+)
+
+// sharedRateLimiter 返回配置对应的限流器。额度按供应商与密钥区分，
+// 使用同一密钥的不同配置共享同一个状态文件
+func sharedRateLimiter(config types.APIConfig) *rateLimiter {
+	sum := sha256.Sum256([]byte(config.Provider + "\x00" + config.APIKey + "\x00" + config.AccessKeyID))
+	path := filepath.Join(rateLimitDir(), hex.EncodeToString(sum[:8])+".json")
+	key := fmt.Sprintf("%s:%d:%d", path, config.RPM, config.TPM)
+	
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	
+	if l, ok := rateLimiters[key]; ok {
+		return l
+	}
+	l := &rateLimiter{rpm: config.RPM, tpm: config.TPM, path: path}
+	rateLimiters[key] = l
+	return l
+}
+
+// reserve 尝试扣除一次请求与 tokens 的额度，额度不足时不扣除，
+// 返回需要等待的时间与不足的限制
+func (l *rateLimiter) reserve(ctx context.Context, tokens int) (time.Duration, string, error) {
+	var wait time.Duration
+	var reason string
+	err := l.update(ctx, func(b *rateBucket) bool {
+		if l.rpm > 0 && b.Requests < 1 {
+			wait = refillTime(1-b.Requests, l.rpm)
+			reason = "rpm"
+		}
+		// 超过桶容量的请求只需等到桶满
+		need := float64(min(tokens, l.tpm))
+		if l.tpm > 0 && b.Tokens < need {
+			if w := refillTime(need-b.Tokens, l.tpm); w > wait {
+				wait, reason = w, "tpm"
+			}
+		}
+		if wait > 0 {
+			return false
+		}
+	
+		if l.rpm > 0 {
+			b.Requests--
+		}
+		if l.tpm > 0 {
+			b.Tokens -= need
+		}
+		return true
+	})
+	return wait, reason, err
+}
+
+// adjust 按实际用量与估算的差值修正 token 余量，余量可以为负，之后的请求会等待补足
+func (l *rateLimiter) adjust(delta int) error {
+	if l.tpm == 0 || delta == 0 {
+		return nil
+	}
+	return l.update(context.Background(), func(b *rateBucket) bool {
+		b.Tokens -= float64(delta)
+		return true
+	})
+}
+
+// update 在持有锁的情况下读取并补充余量，fn 返回 true 时保存修改
+func (l *rateLimiter) update(ctx context.Context, fn func(b *rateBucket) bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	unlock, err := fileutil.Lock(ctx, l.path+".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	
+	now := time.Now()
+	b := rateBucket{Requests: float64(l.rpm), Tokens: float64(l.tpm), Updated: now}
+	if data, err := os.ReadFile(l.path); err == nil && json.Unmarshal(data, &b) == nil {
+		elapsed := now.Sub(b.Updated).Minutes()
+		if elapsed > 0 {
+			b.Requests += elapsed * float64(l.rpm)
+			b.Tokens += elapsed * float64(l.tpm)
+		}
+		// 其他配置可能使用更高的额度，按本配置的容量截断
+		b.Requests = min(b.Requests, float64(l.rpm))
+		b.Tokens = min(b.Tokens, float64(l.tpm))
+		b.Updated = now
+	}
+	
+	if !fn(&b) {
+		return nil
+	}
+	
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(l.path, data, 0600)
+}
+
+// refillTime 返回以每分钟 perMinute 的速度补充 amount 所需的时间
+func refillTime(amount float64, perMinute int) time.Duration {
+	return time.Duration(amount / float64(perMinute) * float64(time.Minute))
+}
+
+// rateLimitTransport 在每次发送前按限流器的额度等待。
+// TPM 额度按请求体估算扣除，响应读完后按其中报告的 total_tokens 修正
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	
+	estimate := 0
+	if t.limiter.tpm > 0 && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			estimate = estimateRequestTokens(data)
+		}
+	}
+	
+	for {
+		wait, reason, err := t.limiter.reserve(ctx, estimate)
+		if err != nil {
+			return nil, err
+		}
+		if wait == 0 {
+			break
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, fmt.Errorf("client rate limit (%s) requires waiting %.1fs, longer than the request timeout", reason, wait.Seconds())
+		}
+	
+		notifyRateLimit(ctx, RateLimitEvent{Wait: wait, Reason: reason})
+		if err := fileutil.SleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+	
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || t.limiter.tpm == 0 {
+		return resp, err
+	}
+	
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		done: func(data []byte, complete bool) {
+			if used, ok := reportedTotalTokens(data); ok {
+				t.limiter.adjust(used - estimate)
+			}
+		},
+	}
+	return resp, nil
+}
+
+// imageTokenEstimate 是一张内联图片的估算 token 数，图片按尺寸计费，无法从 base64 长度推算
+const imageTokenEstimate = 1000
+
+var inlineImagePattern = regexp.MustCompile(`data:image/[a-z]+;base64,[A-Za-z0-9+/=]+`)
+
+// estimateRequestTokens 与 TokenEstimator 相同的粗略估算：ASCII 字符 4 个 1 token，其他字符 2 个 1 token。
+// 内联图片按固定值估算
+func estimateRequestTokens(body []byte) int {
+	images := 0
+	body = inlineImagePattern.ReplaceAllFunc(body, func([]byte) []byte {
+		images++
+		return nil
+	})
+	
+	total := 0
+	for _, r := range string(body) {
+		if r <= 255 {
+			total++
+		} else {
+			total += 2
+		}
+	}
+	return total/4 + images*imageTokenEstimate
+}
+
+var totalTokensPattern = regexp.MustCompile(`"total_tokens"\s*:\s*(\d+)`)
+
+// reportedTotalTokens 取响应中最后出现的 total_tokens，流式响应的用量位于最后一个事件
+func reportedTotalTokens(body []byte) (int, bool) {
+	matches := totalTokensPattern.FindAllSubmatch(body, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(matches[len(matches)-1][1]))
+	return n, err == nil
+}
@@ -0,0 +1,120 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestRateLimiter(t *testing.T, rpm, tpm int) *rateLimiter {
+	t.Helper()
+	return &rateLimiter{rpm: rpm, tpm: tpm, path: filepath.Join(t.TempDir(), "bucket.json")}
+}
+
+// nearly 判断等待时间与期望值的差距在测试运行的耗时以内
+func nearly(got, want time.Duration) bool {
+	diff := got - want
+	return diff > -time.Second && diff < time.Second
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	type step struct {
+		tokens int
+		wait   time.Duration // 0 表示应立即扣除
+		reason string
+	}
+	tests := []struct {
+		name     string
+		rpm, tpm int
+		steps    []step
+	}{
+		{"rpm exhausted", 2, 0, []step{
+			{0, 0, ""},
+			{0, 0, ""},
+			{0, 30 * time.Second, "rpm"},
+			{0, 30 * time.Second, "rpm"}, // 额度不足时不扣除
+		}},
+		{"tpm exhausted", 0, 1000, []step{
+			{600, 0, ""},
+			{600, 12 * time.Second, "tpm"},
+			{400, 0, ""},
+		}},
+		{"request larger than bucket waits for a full bucket", 0, 1000, []step{
+			{5000, 0, ""},
+			{5000, time.Minute, "tpm"},
+		}},
+		{"longer wait wins", 1, 600, []step{
+			{600, 0, ""},
+			{300, time.Minute, "rpm"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestRateLimiter(t, tt.rpm, tt.tpm)
+			for i, s := range tt.steps {
+				wait, reason, err := l.reserve(context.Background(), s.tokens)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if reason != s.reason || !nearly(wait, s.wait) || (s.wait == 0 && wait != 0) {
+					t.Errorf("step %d: reserve(%d) = %v, %q; want %v, %q", i, s.tokens, wait, reason, s.wait, s.reason)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l := newTestRateLimiter(t, 60, 0)
+	// 30 秒前余量为 0，按每分钟 60 次应已补充 30 次
+	data, _ := json.Marshal(rateBucket{Requests: 0, Updated: time.Now().Add(-30 * time.Second)})
+	if err := os.WriteFile(l.path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _, err := l.reserve(context.Background(), 0); err != nil || wait != 0 {
+		t.Fatalf("reserve after refill = %v, %v", wait, err)
+	}
+	
+	var b rateBucket
+	data, _ = os.ReadFile(l.path)
+	if err := json.Unmarshal(data, &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Requests < 28.5 || b.Requests > 29.5 {
+		t.Errorf("requests left = %v, want about 29", b.Requests)
+	}
+}
+
+func TestRateLimiterAdjust(t *testing.T) {
+	tests := []struct {
+		name     string
+		estimate int
+		actual   int
+		next     int
+		wait     time.Duration
+	}{
+		{"underestimate leaves a deficit", 100, 1100, 1, 6 * time.Second},
+		{"overestimate is refunded", 900, 100, 800, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestRateLimiter(t, 0, 1000)
+			if _, _, err := l.reserve(context.Background(), tt.estimate); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.adjust(tt.actual - tt.estimate); err != nil {
+				t.Fatal(err)
+			}
+			wait, _, err := l.reserve(context.Background(), tt.next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !nearly(wait, tt.wait) || (tt.wait == 0 && wait != 0) {
+				t.Errorf("reserve(%d) after adjust = %v, want %v", tt.next, wait, tt.wait)
+			}
+		})
+	}
+}
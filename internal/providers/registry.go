@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// Constructor 根据配置创建供应商实例
+type Constructor func(config types.APIConfig) (types.AIProvider, error)
+
+// Registration 描述一个可通过 profiles.json 中 provider 字段选用的供应商
+type Registration struct {
+	Name        string                             // provider 字段的取值，如 "deepseek"
+	Description string                             // 在配置向导中显示的说明
+	New         Constructor                        // 构造函数
+	Validate    func(config types.APIConfig) error // 检查必填字段，可为空
+	Fields      []string                           // 配置向导需要询问的 APIConfig JSON 字段
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Registration)
+)
+
+// Register 注册一个供应商，通常在 init 中调用。
+// 名称为空、缺少构造函数或重复注册时 panic
+func Register(reg Registration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	
+	if reg.Name == "" || reg.New == nil {
+		panic("providers: Register requires a name and a constructor")
+	}
+	if _, exists := registry[reg.Name]; exists {
+		panic("providers: Register called twice for " + reg.Name)
+	}
+	registry[reg.Name] = reg
+}
+
+// Lookup 按名称查找已注册的供应商
+func Lookup(name string) (Registration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	
+	reg, ok := registry[name]
+	return reg, ok
+}
+
+// Registered 按名称顺序返回所有已注册的供应商
+func Registered() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	
+	regs := make([]Registration, 0, len(registry))
+	for _, reg := range registry {
+		regs = append(regs, reg)
+	}
+	sort.Slice(regs, func(i, j int) bool {
+		return regs[i].Name < regs[j].Name
+	})
+	return regs
+}
+
+// ValidateConfig 使用注册的校验函数检查配置
+func ValidateConfig(config types.APIConfig) error {
+	reg, ok := Lookup(config.Provider)
+	if !ok {
+		return fmt.Errorf("unsupported provider: %s", config.Provider)
+	}
+	if reg.Validate == nil {
+		return nil
+	}
+	return reg.Validate(config)
+}
@@ -0,0 +1,215 @@
+package providers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mrand "math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+const (
+	defaultMaxRetries    = 2
+	defaultRetryDelay    = 500 * time.Millisecond
+	defaultRetryMaxDelay = 20 * time.Second
+	
+	// maxRetryAfter 服务端要求等待超过该时长时不再重试，直接返回响应
+	maxRetryAfter = 2 * time.Minute
+)
+
+// RetryEvent 描述一次即将进行的重试
+type RetryEvent struct {
+	Attempt    int           // 第几次重试，从 1 开始
+	MaxRetries int           // 最大重试次数
+	Wait       time.Duration // 重试前的等待时间
+	Reason     string        // 触发重试的状态码或错误
+}
+
+type retryNotifierKey struct{}
+
+// WithRetryNotifier 返回携带重试回调的上下文，供应商重试前会调用 fn
+func WithRetryNotifier(ctx context.Context, fn func(RetryEvent)) context.Context {
+	return context.WithValue(ctx, retryNotifierKey{}, fn)
+}
+
+func notifyRetry(ctx context.Context, event RetryEvent) {
+	if fn, ok := ctx.Value(retryNotifierKey{}).(func(RetryEvent)); ok && fn != nil {
+		fn(event)
+	}
+}
+
+// retryTransport 对可安全重试的失败进行指数退避重试。
+// 为避免重复计费，只在请求确定未被处理时重试：
+// 429/502/503/504 状态码，以及连接尚未建立的网络错误
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+func newRetryTransport(base http.RoundTripper, config types.APIConfig) *retryTransport {
+	t := &retryTransport{
+		base:       base,
+		maxRetries: defaultMaxRetries,
+		baseDelay:  defaultRetryDelay,
+		maxDelay:   defaultRetryMaxDelay,
+	}
+	
+	if config.MaxRetries < 0 {
+		t.maxRetries = 0
+	} else if config.MaxRetries > 0 {
+		t.maxRetries = config.MaxRetries
+	}
+	if config.RetryDelay > 0 {
+		t.baseDelay = time.Duration(config.RetryDelay) * time.Millisecond
+	}
+	if config.RetryMaxDelay > 0 {
+		t.maxDelay = time.Duration(config.RetryMaxDelay) * time.Millisecond
+	}
+	
+	return t
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// 无法重放请求体时不重试；阿里云 OpenAPI 的签名包含一次性的 nonce，
+	// 原样重发会被服务端拒绝，须由调用方重新签名
+	if t.maxRetries == 0 || (req.Body != nil && req.GetBody == nil) || req.Header.Get("x-acs-signature-nonce") != "" {
+		return t.base.RoundTrip(req)
+	}
+	
+	// 同一逻辑请求的所有尝试共用一个幂等键，支持该头的服务端可据此去重
+	if req.Header.Get("Idempotency-Key") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Idempotency-Key", newIdempotencyKey())
+	}
+	
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+		
+		resp, err := t.base.RoundTrip(attemptReq)
+		if attempt >= t.maxRetries {
+			return resp, err
+		}
+		
+		wait, reason, retry := t.shouldRetry(resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		
+		if resp != nil {
+			// 丢弃响应体以便复用连接
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		
+		notifyRetry(req.Context(), RetryEvent{
+			Attempt:    attempt + 1,
+			MaxRetries: t.maxRetries,
+			Wait:       wait,
+			Reason:     reason,
+		})
+		
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetry 判断是否重试，并给出等待时间与原因
+func (t *retryTransport) shouldRetry(resp *http.Response, err error, attempt int) (time.Duration, string, bool) {
+	if err != nil {
+		if !isUnsentError(err) {
+			return 0, "", false
+		}
+		return t.backoff(attempt), err.Error(), true
+	}
+	
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return 0, "", false
+	}
+	
+	wait := t.backoff(attempt)
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		if retryAfter > maxRetryAfter {
+			return 0, "", false
+		}
+		wait = retryAfter
+	}
+	return wait, resp.Status, true
+}
+
+// backoff 计算带抖动的指数退避时间：一半固定，一半随机
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	delay := float64(t.baseDelay) * math.Pow(2, float64(attempt))
+	if delay > float64(t.maxDelay) {
+		delay = float64(t.maxDelay)
+	}
+	half := time.Duration(delay / 2)
+	return half + time.Duration(mrand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter 解析秒数或 HTTP 日期格式的 Retry-After
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		wait := time.Until(when)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// isUnsentError 判断错误是否发生在请求发出之前（如 DNS 解析或建立连接失败），
+// 只有这类错误可以确定服务端没有处理请求
+func isUnsentError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func newIdempotencyKey() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("akasha-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
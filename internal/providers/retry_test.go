@@ -0,0 +1,115 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+		ok    bool
+	}{
+		{"empty", "", 0, 0, false},
+		{"seconds", "3", 3 * time.Second, 3 * time.Second, true},
+		{"zero", "0", 0, 0, true},
+		{"negative", "-1", 0, 0, false},
+		{"garbage", "soon", 0, 0, false},
+		{"future date", time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second, true},
+		{"past date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if ok != tt.ok || got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %v, %v; want [%v, %v], %v", tt.value, got, ok, tt.min, tt.max, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	rt := &retryTransport{baseDelay: 100 * time.Millisecond, maxDelay: time.Second}
+	tests := []struct {
+		attempt int
+		full    time.Duration // 抖动前的退避时间，结果落在 [full/2, full]
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second}, // 超过上限
+		{20, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			if got := rt.backoff(tt.attempt); got < tt.full/2 || got > tt.full {
+				t.Fatalf("backoff(%d) = %v, want [%v, %v]", tt.attempt, got, tt.full/2, tt.full)
+			}
+		}
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	rt := &retryTransport{baseDelay: 100 * time.Millisecond, maxDelay: time.Second}
+	response := func(status int, retryAfter string) *http.Response {
+		resp := &http.Response{StatusCode: status, Status: fmt.Sprintf("%d %s", status, http.StatusText(status)), Header: http.Header{}}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp
+	}
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	
+	tests := []struct {
+		name  string
+		resp  *http.Response
+		err   error
+		retry bool
+		wait  time.Duration // 非 0 时要求等待时间与之相等
+	}{
+		{"ok", response(200, ""), nil, false, 0},
+		{"bad request", response(400, ""), nil, false, 0},
+		{"server error may have been processed", response(500, ""), nil, false, 0},
+		{"rate limited", response(429, ""), nil, true, 0},
+		{"bad gateway", response(502, ""), nil, true, 0},
+		{"unavailable", response(503, ""), nil, true, 0},
+		{"gateway timeout", response(504, ""), nil, true, 0},
+		{"retry after honored", response(429, "7"), nil, true, 7 * time.Second},
+		{"retry after too long", response(429, "600"), nil, false, 0},
+		{"dial error", nil, dialErr, true, 0},
+		{"temporary dns error", nil, &net.DNSError{Err: "server misbehaving", IsTemporary: true}, true, 0},
+		{"missing host", nil, &net.DNSError{Err: "no such host", IsNotFound: true}, false, 0},
+		{"read error after send", nil, readErr, false, 0},
+		{"canceled", nil, context.Canceled, false, 0},
+		{"deadline", nil, fmt.Errorf("request: %w", context.DeadlineExceeded), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, reason, retry := rt.shouldRetry(tt.resp, tt.err, 0)
+			if retry != tt.retry {
+				t.Fatalf("retry = %v, want %v", retry, tt.retry)
+			}
+			if !retry {
+				return
+			}
+			if reason == "" {
+				t.Error("empty reason")
+			}
+			if tt.wait != 0 && wait != tt.wait {
+				t.Errorf("wait = %v, want %v", wait, tt.wait)
+			}
+			if tt.wait == 0 && (wait < 50*time.Millisecond || wait > 100*time.Millisecond) {
+				t.Errorf("wait = %v, want backoff within [50ms, 100ms]", wait)
+			}
+		})
+	}
+}
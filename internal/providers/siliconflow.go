@@ -1,85 +1,192 @@
 package providers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 	
 	"github.com/yantianyv/AkashaTerminal/pkg/types"
 )
 
+func init() {
+	Register(Registration{
+		Name:        "siliconflow",
+		Description: "硅基流动 SiliconFlow",
+		Validate:    validateSiliconFlowConfig,
+		Fields:      []string{"api_key", "api_base", "model", "max_tokens"},
+		New: func(config types.APIConfig) (types.AIProvider, error) {
+			return NewSiliconFlowProvider(config)
+		},
+	})
+}
+
 type SiliconFlowProvider struct {
 	config types.APIConfig
+	client *http.Client
 }
 
-func NewProvider(config types.APIConfig) (*SiliconFlowProvider, error) {
+// validateSiliconFlowConfig 检查 siliconflow 配置的必填字段
+func validateSiliconFlowConfig(config types.APIConfig) error {
 	if config.APIKey == "" {
-		return nil, fmt.Errorf("SiliconFlow API key is required")
+		return fmt.Errorf("SiliconFlow API key is required")
+	}
+	return nil
+}
+
+func NewSiliconFlowProvider(config types.APIConfig) (*SiliconFlowProvider, error) {
+	if err := validateSiliconFlowConfig(config); err != nil {
+		return nil, err
+	}
+	
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
 	}
 	
 	return &SiliconFlowProvider{
 		config: config,
+		client: client,
 	}, nil
 }
 
-func (p *SiliconFlowProvider) SendRequest(prompt string, state interface{}) (string, error) {
-	url := "https://api.siliconflow.com/v1/completions"
+// endpoint 返回 chat completions 地址，APIBase 可覆盖默认值
+func (p *SiliconFlowProvider) endpoint() string {
 	if p.config.APIBase != "" {
-		url = p.config.APIBase
+		return p.config.APIBase
+	}
+	return "https://api.siliconflow.com/v1/chat/completions"
+}
+
+// newRequest 构造 chat completions 请求
+func (p *SiliconFlowProvider) newRequest(ctx context.Context, chat chatRequest) (*http.Request, error) {
+	if err := checkImages(p, chat.Messages); err != nil {
+		return nil, err
 	}
 	
+	chat.Params = generationParams(ctx, p.config.GenerationParams)
+	chat.FormatMode = p.formatMode()
+	// SiliconFlow 不支持 seed 与 presence_penalty
+	chat.Params.Seed = nil
+	chat.Params.PresencePenalty = nil
+	
 	requestBody := map[string]interface{}{
-		"model":       p.config.Model,
-		"prompt":      prompt,
-		"max_tokens":  p.config.MaxTokens,
-		"temperature": 0.7,
+		"model":      p.config.Model,
+		"max_tokens": p.config.MaxTokens,
 	}
 	
 	if strings.HasPrefix(p.config.Model, "yi-") {
 		requestBody["quant_mode"] = "int8"
 	}
 	
+	chat.apply(requestBody)
+	
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint(), bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	return req, nil
+}
+
+func (p *SiliconFlowProvider) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	return p.SendWithTools(ctx, messages, nil)
+}
+
+func (p *SiliconFlowProvider) StreamRequest(ctx context.Context, messages []types.Message, onChunk func(string)) (types.Response, error) {
+	return p.stream(ctx, chatRequest{Messages: messages, Stream: true, StreamUsage: true}, onChunk)
+}
+
+// stream 发送流式请求，每收到一段文本调用一次 onChunk
+func (p *SiliconFlowProvider) stream(ctx context.Context, chat chatRequest, onChunk func(string)) (types.Response, error) {
+	req, err := p.newRequest(ctx, chat)
+	if err != nil {
+		return types.Response{}, err
+	}
 	
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", err
+		return types.Response{}, err
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("SiliconFlow API error: %s - %s", resp.Status, string(body))
+		return types.Response{}, newAPIError("SiliconFlow", resp)
 	}
 	
-	var response struct {
-		Choices []struct {
-			Text string `json:"text"`
-		} `json:"choices"`
+	response, err := readChatStream(resp.Body, onChunk, reasoningHandler(ctx))
+	if err != nil {
+		return types.Response{}, err
+	}
+	// 部分模型忽略 stream_options，没有用量时按字符估算
+	return withModel(withEstimatedUsage(response, chat.Messages), p.GetModel()), nil
+}
+
+func (p *SiliconFlowProvider) SendStructured(ctx context.Context, messages []types.Message, format types.ResponseFormat, onChunk func(string)) (types.Response, error) {
+	if onChunk == nil {
+		return p.send(ctx, chatRequest{Messages: messages, Format: &format})
+	}
+	return p.stream(ctx, chatRequest{Messages: messages, Stream: true, StreamUsage: true, Format: &format}, onChunk)
+}
+
+func (p *SiliconFlowProvider) StreamWithTools(ctx context.Context, messages []types.Message, tools []types.Tool, onChunk func(string)) (types.Response, error) {
+	return p.stream(ctx, chatRequest{Messages: messages, Tools: tools, Stream: true, StreamUsage: true}, onChunk)
+}
+
+func (p *SiliconFlowProvider) SendWithTools(ctx context.Context, messages []types.Message, tools []types.Tool) (types.Response, error) {
+	return p.send(ctx, chatRequest{Messages: messages, Tools: tools})
+}
+
+// send 发送一次性请求
+func (p *SiliconFlowProvider) send(ctx context.Context, chat chatRequest) (types.Response, error) {
+	req, err := p.newRequest(ctx, chat)
+	if err != nil {
+		return types.Response{}, err
 	}
 	
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", err
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return types.Response{}, err
 	}
+	defer resp.Body.Close()
 	
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no completions received from SiliconFlow")
+	if resp.StatusCode != http.StatusOK {
+		return types.Response{}, newAPIError("SiliconFlow", resp)
 	}
 	
-	return response.Choices[0].Text, nil
+	response, err := decodeChatResponse(resp.Body, "SiliconFlow")
+	if err != nil {
+		return types.Response{}, err
+	}
+	return withModel(response, p.GetModel()), nil
+}
+
+// ListModels 列出账号可用的模型
+func (p *SiliconFlowProvider) ListModels(ctx context.Context) ([]types.ModelInfo, error) {
+	return listModels(ctx, p.client, modelsURL(p.endpoint()), p.config.APIKey, "SiliconFlow")
+}
+
+// Embed 使用向量模型将文本转换为向量，默认 BAAI/bge-m3
+func (p *SiliconFlowProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedTexts(ctx, p.client, embeddingsURL(p.endpoint()), p.config.APIKey, "SiliconFlow",
+		p.EmbeddingInfo(), p.config.EmbeddingDimensions > 0, texts)
+}
+
+func (p *SiliconFlowProvider) EmbeddingInfo() types.EmbeddingInfo {
+	model := p.config.EmbeddingModel
+	if model == "" {
+		model = "BAAI/bge-m3"
+	}
+	return embeddingInfo(model, p.config.EmbeddingDimensions)
 }
 
 func (p *SiliconFlowProvider) GetName() string {
@@ -90,11 +197,29 @@ func (p *SiliconFlowProvider) GetModel() string {
 	return p.config.Model
 }
 
-func (p *SiliconFlowProvider) SupportsFeature(feature string) bool {
+func (p *SiliconFlowProvider) SupportsFeature(feature types.Capability) bool {
 	switch feature {
-	case "quantization":
+	case types.CapQuantization:
 		return strings.HasPrefix(p.config.Model, "yi-")
+	case types.CapMultimodal:
+		// 视觉模型如 Qwen/Qwen2.5-VL-72B-Instruct、deepseek-ai/deepseek-vl2
+		return strings.Contains(strings.ToLower(p.config.Model), "-vl")
+	case types.CapTools:
+		// 仅部分托管模型支持函数调用
+		return strings.HasPrefix(p.config.Model, "deepseek-ai/") ||
+			strings.HasPrefix(p.config.Model, "Qwen/") ||
+			strings.HasPrefix(p.config.Model, "THUDM/glm-4")
+	case types.CapStructuredOutput:
+		return p.formatMode() != formatNone
 	default:
 		return false
 	}
 }
+
+// formatMode 返回支持的 response_format，JSON 模式与函数调用支持的模型范围相同
+func (p *SiliconFlowProvider) formatMode() formatMode {
+	if p.SupportsFeature(types.CapTools) {
+		return formatJSONObject
+	}
+	return formatNone
+}
@@ -0,0 +1,232 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// errStreamDone 表示收到了 data: [DONE]
+var errStreamDone = errors.New("stream done")
+
+type reasoningHandlerKey struct{}
+
+// WithReasoningHandler 返回携带思考过程回调的上下文，
+// 推理模型每输出一段思考过程调用一次 fn，与回复正文分开
+func WithReasoningHandler(ctx context.Context, fn func(string)) context.Context {
+	return context.WithValue(ctx, reasoningHandlerKey{}, fn)
+}
+
+// reasoningHandler 返回上下文中的思考过程回调，未设置时返回 nil
+func reasoningHandler(ctx context.Context) func(string) {
+	fn, _ := ctx.Value(reasoningHandlerKey{}).(func(string))
+	return fn
+}
+
+// Stream 优先以流式方式发送请求，供应商不支持流式时退回一次性请求，
+// 此时整段回复作为唯一的片段交给 onChunk
+func Stream(ctx context.Context, provider types.AIProvider, messages []types.Message, onChunk func(string)) (types.Response, error) {
+	if sp, ok := provider.(types.StreamingProvider); ok {
+		return sp.StreamRequest(ctx, messages, onChunk)
+	}
+	
+	response, err := provider.SendRequest(ctx, messages)
+	if err != nil {
+		return types.Response{}, err
+	}
+	if fn := reasoningHandler(ctx); fn != nil && response.Reasoning != "" {
+		fn(response.Reasoning)
+	}
+	if onChunk != nil {
+		onChunk(response.Content)
+	}
+	return response, nil
+}
+
+// StreamWithTools 优先以流式方式发送带工具定义的请求，供应商不支持时退回 SendWithTools，
+// 此时回复中的文本作为唯一的片段交给 onChunk
+func StreamWithTools(ctx context.Context, provider types.ToolCallingProvider, messages []types.Message,
+	tools []types.Tool, onChunk func(string)) (types.Response, error) {
+	
+	if sp, ok := provider.(types.StreamingToolProvider); ok {
+		return sp.StreamWithTools(ctx, messages, tools, onChunk)
+	}
+	
+	response, err := provider.SendWithTools(ctx, messages, tools)
+	if err != nil {
+		return types.Response{}, err
+	}
+	if fn := reasoningHandler(ctx); fn != nil && response.Reasoning != "" {
+		fn(response.Reasoning)
+	}
+	if onChunk != nil && response.Content != "" {
+		onChunk(response.Content)
+	}
+	return response, nil
+}
+
+// SupportsStructuredOutput 报告供应商是否可以按 ResponseFormat 约束回复
+func SupportsStructuredOutput(provider types.AIProvider) bool {
+	_, ok := provider.(types.StructuredOutputProvider)
+	return ok && provider.SupportsFeature(types.CapStructuredOutput)
+}
+
+// StreamStructured 要求回复为符合 format 的 JSON 对象，onChunk 为 nil 时发送一次性请求。
+// 供应商不支持结构化输出时返回错误，而不是忽略格式要求
+func StreamStructured(ctx context.Context, provider types.AIProvider, messages []types.Message,
+	format types.ResponseFormat, onChunk func(string)) (types.Response, error) {
+	
+	if !SupportsStructuredOutput(provider) {
+		return types.Response{}, fmt.Errorf("%s does not support structured output", provider.GetName())
+	}
+	return provider.(types.StructuredOutputProvider).SendStructured(ctx, messages, format, onChunk)
+}
+
+// readEventStream 解析 text/event-stream，对每个事件的 data 调用 onData，
+// 收到 [DONE] 或数据结束时返回
+func readEventStream(r io.Reader, onData func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		payload := strings.Join(data, "\n")
+		data = data[:0]
+		if payload == "[DONE]" {
+			return errStreamDone
+		}
+		return onData(payload)
+	}
+	
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return ignoreStreamDone(err)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // 注释/心跳
+		}
+		
+		field, value, _ := strings.Cut(line, ":")
+		if field == "data" {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return ignoreStreamDone(dispatch())
+}
+
+func ignoreStreamDone(err error) error {
+	if errors.Is(err, errStreamDone) {
+		return nil
+	}
+	return err
+}
+
+// readChatStream 拼接 OpenAI 兼容流式接口的增量内容，返回完整回复。
+// 同时兼容 chat 接口的 delta.content 和 completions 接口的 text；
+// 推理模型的 delta.reasoning_content 交给 onReasoning，不计入回复正文。
+// 用量通常出现在最后一个片段中，未返回时为零值
+func readChatStream(r io.Reader, onChunk, onReasoning func(string)) (types.Response, error) {
+	var content, reasoning strings.Builder
+	var result types.Response
+	var toolCalls []types.ToolCall
+	
+	err := readEventStream(r, func(data string) error {
+		var chunk struct {
+			Model   string `json:"model"`
+			Choices []struct {
+				Delta struct {
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+					ToolCalls        []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+				Text         string `json:"text"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *chatUsage `json:"usage"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("invalid stream chunk: %v", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("stream error: %s", chunk.Error.Message)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage.usage()
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			result.FinishReason = choice.FinishReason
+		}
+		
+		if choice.Delta.ReasoningContent != "" {
+			reasoning.WriteString(choice.Delta.ReasoningContent)
+			if onReasoning != nil {
+				onReasoning(choice.Delta.ReasoningContent)
+			}
+		}
+		
+		text := choice.Delta.Content + choice.Text
+		if text != "" {
+			content.WriteString(text)
+			if onChunk != nil {
+				onChunk(text)
+			}
+		}
+		
+		// 工具调用按 index 分多个片段到达，参数需要拼接
+		for _, delta := range choice.Delta.ToolCalls {
+			for len(toolCalls) <= delta.Index {
+				toolCalls = append(toolCalls, types.ToolCall{})
+			}
+			call := &toolCalls[delta.Index]
+			if delta.ID != "" {
+				call.ID = delta.ID
+			}
+			if delta.Function.Name != "" {
+				call.Name = delta.Function.Name
+			}
+			call.Arguments += delta.Function.Arguments
+		}
+		return nil
+	})
+	
+	result.Content = content.String()
+	result.ToolCalls = toolCalls
+	result.Reasoning = reasoning.String()
+	return result, err
+}
@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+func TestReadChatStream(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    []string
+		content   string
+		chunks    int
+		toolCalls []types.ToolCall
+	}{
+		{
+			name: "text",
+			events: []string{
+				`{"choices":[{"delta":{"content":"hel"}}]}`,
+				`{"choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+			},
+			content: "hello",
+			chunks:  2,
+		},
+		{
+			name: "text then tool calls split across chunks",
+			events: []string{
+				`{"choices":[{"delta":{"content":"先读取文件"}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read_file","arguments":""}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":"}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","function":{"name":"scan_dir","arguments":"{}"}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"a.go\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			},
+			content: "先读取文件",
+			chunks:  1,
+			toolCalls: []types.ToolCall{
+				{ID: "call_1", Name: "read_file", Arguments: `{"path":"a.go"}`},
+				{ID: "call_2", Name: "scan_dir", Arguments: "{}"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stream strings.Builder
+			for _, event := range tt.events {
+				stream.WriteString("data: " + event + "\n\n")
+			}
+			stream.WriteString("data: [DONE]\n\n")
+			
+			chunks := 0
+			response, err := readChatStream(strings.NewReader(stream.String()), func(string) { chunks++ }, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Content != tt.content || chunks != tt.chunks {
+				t.Errorf("content = %q in %d chunks, want %q in %d", response.Content, chunks, tt.content, tt.chunks)
+			}
+			if !reflect.DeepEqual(response.ToolCalls, tt.toolCalls) {
+				t.Errorf("tool calls = %+v, want %+v", response.ToolCalls, tt.toolCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	
+	"github.com/yantianyv/AkashaTerminal/internal/fileutil"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// defaultPrices 是常用模型的参考价格（每百万 token），以各供应商公布的价格为准，
+// 可在 profiles.json 的 prices 中按模型覆盖。键按 ID 前缀匹配，不区分大小写
+var defaultPrices = map[string]types.ModelPrice{
+	"gpt-4.1":           {Input: 2, CachedInput: 0.5, Output: 8, Currency: "USD"},
+	"gpt-4.1-mini":      {Input: 0.4, CachedInput: 0.1, Output: 1.6, Currency: "USD"},
+	"gpt-4o":            {Input: 2.5, CachedInput: 1.25, Output: 10, Currency: "USD"},
+	"gpt-4o-mini":       {Input: 0.15, CachedInput: 0.075, Output: 0.6, Currency: "USD"},
+	"gpt-4-turbo":       {Input: 10, Output: 30, Currency: "USD"},
+	"gpt-3.5-turbo":     {Input: 0.5, Output: 1.5, Currency: "USD"},
+	"o4-mini":           {Input: 1.1, CachedInput: 0.275, Output: 4.4, Currency: "USD"},
+	"deepseek-chat":     {Input: 2, CachedInput: 0.5, Output: 8, Currency: "CNY"},
+	"deepseek-reasoner": {Input: 4, CachedInput: 1, Output: 16, Currency: "CNY"},
+	"deepseek-v3":       {Input: 2, Output: 8, Currency: "CNY"},
+	"deepseek-r1":       {Input: 4, Output: 16, Currency: "CNY"},
+	"qwen-max":          {Input: 2.4, Output: 9.6, Currency: "CNY"},
+	"qwen-plus":         {Input: 0.8, Output: 2, Currency: "CNY"},
+	"qwen-turbo":        {Input: 0.3, Output: 0.6, Currency: "CNY"},
+}
+
+// Cost 是一次请求的费用
+type Cost struct {
+	Amount    float64
+	Currency  string
+	Priced    bool // 价格表中是否有该模型
+	Estimated bool // 按估算的用量计算
+}
+
+// Budget 是会话花费上限，只与同一币种的花费比较
+type Budget struct {
+	Amount   float64
+	Currency string // 为空时使用当前模型价格的币种
+}
+
+// ParseBudget 解析 "5"、"5USD"、"10 CNY"、"$5"、"¥10" 形式的花费上限
+func ParseBudget(input string) (Budget, error) {
+	s := strings.TrimSpace(input)
+	var b Budget
+	switch {
+	case strings.HasPrefix(s, "$"):
+		b.Currency, s = "USD", strings.TrimPrefix(s, "$")
+	case strings.HasPrefix(s, "¥"), strings.HasPrefix(s, "￥"):
+		b.Currency, s = "CNY", strings.TrimLeft(s, "¥￥")
+	}
+	
+	number := strings.TrimRightFunc(s, func(r rune) bool {
+		return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == ' '
+	})
+	if currency := strings.TrimSpace(s[len(number):]); currency != "" {
+		if b.Currency != "" {
+			return Budget{}, fmt.Errorf("花费上限 %q 的币种重复", input)
+		}
+		b.Currency = strings.ToUpper(currency)
+	}
+	
+	amount, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || amount < 0 {
+		return Budget{}, fmt.Errorf("无效的花费上限 %q，应为金额加可选的币种，如 5USD", input)
+	}
+	b.Amount = amount
+	return b, nil
+}
+
+// CostTracker 按价格表计算每次请求的费用，累计会话花费与历史花费。
+// 历史花费按币种保存在文件中，跨会话累加；同时运行的多个进程在锁文件保护下合并各自的花费
+type CostTracker struct {
+	prices  map[string]types.ModelPrice
+	path    string             // 历史花费的保存位置，为空时不保存
+	budget  Budget             // 会话花费上限，金额为 0 表示不限制
+	session map[string]float64 // 按币种累计
+	total   map[string]float64 // 最近一次读写文件时的历史花费
+}
+
+// NewCostTracker 创建费用统计，overrides 覆盖内置价格，path 为历史花费文件
+func NewCostTracker(overrides map[string]types.ModelPrice, path string) (*CostTracker, error) {
+	prices := make(map[string]types.ModelPrice, len(defaultPrices)+len(overrides))
+	for model, price := range defaultPrices {
+		prices[model] = price
+	}
+	for model, price := range overrides {
+		prices[strings.ToLower(model)] = price
+	}
+	
+	ct := &CostTracker{
+		prices:  prices,
+		path:    path,
+		session: make(map[string]float64),
+		total:   make(map[string]float64),
+	}
+	if path == "" {
+		return ct, nil
+	}
+	
+	total, err := ct.load()
+	if err != nil {
+		return ct, err
+	}
+	ct.total = total
+	return ct, nil
+}
+
+// SetBudget 设置会话花费上限。未指定币种时使用 model 价格的币种，
+// 价格表中没有该模型时无法确定币种，返回错误
+func (ct *CostTracker) SetBudget(budget Budget, model string) error {
+	if budget.Amount > 0 && budget.Currency == "" {
+		price, ok := ct.Price(model)
+		if !ok || price.Currency == "" {
+			return fmt.Errorf("无法确定花费上限的币种（价格表中没有模型 %s），请写明币种，如 -budget 5USD", model)
+		}
+		budget.Currency = price.Currency
+	}
+	ct.budget = budget
+	return nil
+}
+
+// Price 查找模型的价格：先精确匹配，再按最长前缀匹配，
+// 带组织前缀的 ID（如 deepseek-ai/DeepSeek-V3）同时按斜杠后的部分匹配
+func (ct *CostTracker) Price(model string) (types.ModelPrice, bool) {
+	id := strings.ToLower(model)
+	if price, ok := ct.prices[id]; ok {
+		return price, true
+	}
+	
+	candidates := []string{id}
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		candidates = append(candidates, id[i+1:])
+	}
+	
+	best := ""
+	for prefix := range ct.prices {
+		for _, c := range candidates {
+			if strings.HasPrefix(c, prefix) && len(prefix) > len(best) {
+				best = prefix
+			}
+		}
+	}
+	if best == "" {
+		return types.ModelPrice{}, false
+	}
+	return ct.prices[best], true
+}
+
+// Record 计算一次请求的费用并计入会话与历史花费。
+// 命中缓存的输入按缓存价格计费；思考 token 已包含在输出中；
+// 供应商未报告用量时应传入 EstimateUsage 的估算值，否则费用为 0，花费上限不会生效
+func (ct *CostTracker) Record(model string, usage types.Usage) (Cost, error) {
+	price, ok := ct.Price(model)
+	if !ok {
+		return Cost{}, nil
+	}
+	
+	cachedPrice := price.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = price.Input
+	}
+	cached := min(usage.CachedTokens, usage.PromptTokens)
+	amount := (float64(usage.PromptTokens-cached)*price.Input +
+		float64(cached)*cachedPrice +
+		float64(usage.CompletionTokens)*price.Output) / 1e6
+	
+	cost := Cost{Amount: amount, Currency: price.Currency, Priced: true, Estimated: usage.Estimated}
+	if amount == 0 {
+		return cost, nil
+	}
+	
+	ct.session[price.Currency] += amount
+	return cost, ct.addTotal(price.Currency, amount)
+}
+
+// load 读取历史花费文件，文件不存在时返回空表
+func (ct *CostTracker) load() (map[string]float64, error) {
+	total := make(map[string]float64)
+	data, err := os.ReadFile(ct.path)
+	if os.IsNotExist(err) {
+		return total, nil
+	}
+	if err != nil {
+		return total, err
+	}
+	if err := json.Unmarshal(data, &total); err != nil {
+		return total, fmt.Errorf("load spending %s: %v", ct.path, err)
+	}
+	return total, nil
+}
+
+// costLockTimeout 是等待花费文件锁的最长时间
+const costLockTimeout = 30 * time.Second
+
+// addTotal 在锁文件保护下读取最新的历史花费，加上本次金额后写回，
+// 其他进程在此期间记录的花费不会被覆盖
+func (ct *CostTracker) addTotal(currency string, amount float64) error {
+	if ct.path == "" {
+		ct.total[currency] += amount
+		return nil
+	}
+	
+	// 记录发生在请求结束后，不使用可能已取消的请求上下文；锁的等待时间有上限
+	ctx, cancel := context.WithTimeout(context.Background(), costLockTimeout)
+	defer cancel()
+	unlock, err := fileutil.Lock(ctx, ct.path+".lock")
+	if err != nil {
+		ct.total[currency] += amount
+		return err
+	}
+	defer unlock()
+	
+	total, err := ct.load()
+	if err != nil {
+		ct.total[currency] += amount
+		return err
+	}
+	total[currency] += amount
+	ct.total = total
+	
+	data, err := json.MarshalIndent(total, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(ct.path, data, 0600)
+}
+
+// CheckBudget 在会话中与上限同币种的花费达到上限时返回错误，其他币种的花费不计入
+func (ct *CostTracker) CheckBudget() error {
+	if ct.budget.Amount <= 0 {
+		return nil
+	}
+	for currency, spent := range ct.session {
+		if strings.EqualFold(currency, ct.budget.Currency) && spent >= ct.budget.Amount {
+			return fmt.Errorf("本次会话花费 %s 已达到上限 %s，不再发送请求",
+				FormatMoney(spent, currency), FormatMoney(ct.budget.Amount, ct.budget.Currency))
+		}
+	}
+	return nil
+}
+
+// SessionSummary 返回会话花费，多个币种以 + 连接
+func (ct *CostTracker) SessionSummary() string {
+	return formatTotals(ct.session)
+}
+
+// TotalSummary 返回历史累计花费
+func (ct *CostTracker) TotalSummary() string {
+	return formatTotals(ct.total)
+}
+
+func formatTotals(totals map[string]float64) string {
+	currencies := sortedCurrencies(totals)
+	if len(currencies) == 0 {
+		return FormatMoney(0, "")
+	}
+	
+	parts := make([]string, 0, len(currencies))
+	for _, currency := range currencies {
+		parts = append(parts, FormatMoney(totals[currency], currency))
+	}
+	return strings.Join(parts, " + ")
+}
+
+func sortedCurrencies(totals map[string]float64) []string {
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// FormatMoney 以货币符号显示金额，小额保留更多位数
+func FormatMoney(amount float64, currency string) string {
+	digits := 2
+	if amount < 1 {
+		digits = 4
+	}
+	
+	switch strings.ToUpper(currency) {
+	case "CNY", "RMB":
+		return fmt.Sprintf("¥%.*f", digits, amount)
+	case "USD":
+		return fmt.Sprintf("$%.*f", digits, amount)
+	case "":
+		return fmt.Sprintf("%.*f", digits, amount)
+	default:
+		return fmt.Sprintf("%.*f %s", digits, amount, currency)
+	}
+}
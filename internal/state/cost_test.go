@@ -0,0 +1,117 @@
+package state
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+func TestParseBudget(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Budget
+		wantErr bool
+	}{
+		{"5", Budget{5, ""}, false},
+		{"5USD", Budget{5, "USD"}, false},
+		{"10 cny", Budget{10, "CNY"}, false},
+		{"$1.5", Budget{1.5, "USD"}, false},
+		{"¥10", Budget{10, "CNY"}, false},
+		{"$5USD", Budget{}, true},
+		{"abc", Budget{}, true},
+		{"-1", Budget{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBudget(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("ParseBudget(%q) = %+v, %v; want %+v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// 上限只与同币种的花费比较
+func TestCheckBudgetCurrency(t *testing.T) {
+	ct, _ := NewCostTracker(nil, "")
+	if err := ct.SetBudget(Budget{Amount: 1}, "gpt-4o"); err != nil {
+		t.Fatal(err)
+	}
+	
+	// 约 ¥2 的 deepseek 花费不计入美元上限
+	ct.Record("deepseek-chat", types.Usage{PromptTokens: 1000000})
+	if err := ct.CheckBudget(); err != nil {
+		t.Fatalf("CNY spending counted against a USD budget: %v", err)
+	}
+	ct.Record("gpt-4o", types.Usage{PromptTokens: 400000}) // $1
+	if err := ct.CheckBudget(); err == nil {
+		t.Fatal("expected budget error after $1 of spending")
+	}
+	
+	if err := ct.SetBudget(Budget{Amount: 1}, "unknown-model"); err == nil {
+		t.Error("expected error for a budget without currency on an unpriced model")
+	}
+}
+
+// 同时运行的多个会话各自记录的花费都应保存下来
+func TestCostTrackerConcurrentTotals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spending.json")
+	
+	const trackers, records = 4, 10
+	var wg sync.WaitGroup
+	for i := 0; i < trackers; i++ {
+		ct, err := NewCostTracker(map[string]types.ModelPrice{"m": {Input: 1, Currency: "USD"}}, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < records; j++ {
+				if _, err := ct.Record("m", types.Usage{PromptTokens: 1000000}); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	
+	ct, err := NewCostTracker(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ct.TotalSummary(), FormatMoney(trackers*records, "USD"); !strings.EqualFold(got, want) {
+		t.Errorf("total = %s, want %s", got, want)
+	}
+}
+
+// 供应商未报告用量时按估算值计费，花费上限仍然生效
+func TestRecordEstimatedUsage(t *testing.T) {
+	ct, _ := NewCostTracker(map[string]types.ModelPrice{"m": {Input: 1e6, Output: 1e6, Currency: "USD"}}, "")
+	if err := ct.SetBudget(Budget{Amount: 100}, "m"); err != nil {
+		t.Fatal(err)
+	}
+	tm := NewTokenManager(8192)
+	
+	reported := types.Usage{PromptTokens: 3, CompletionTokens: 1}
+	if got := tm.EstimateUsage(reported, nil, "reply"); got != reported {
+		t.Errorf("EstimateUsage replaced reported usage: %+v", got)
+	}
+	
+	prompt := []types.Message{{Role: types.RoleUser, Content: strings.Repeat("x", 400)}}
+	usage := tm.EstimateUsage(types.Usage{}, prompt, strings.Repeat("y", 40))
+	if !usage.Estimated || usage.PromptTokens != 100 || usage.CompletionTokens != 10 {
+		t.Fatalf("EstimateUsage = %+v", usage)
+	}
+	cost, err := ct.Record("m", usage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cost.Estimated || cost.Amount != 110 {
+		t.Errorf("cost = %+v, want estimated 110", cost)
+	}
+	if err := ct.CheckBudget(); err == nil {
+		t.Error("expected budget error after estimated spending")
+	}
+}
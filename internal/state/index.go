@@ -0,0 +1,284 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+	
+	"github.com/yantianyv/AkashaTerminal/internal/fileutil"
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// 文件切块参数：按行切分，每块不超过 chunkLines 行或 chunkMaxBytes 字节
+const (
+	chunkLines       = 40
+	chunkMaxBytes    = 2000
+	indexMaxFileSize = 256 * 1024 // 更大的文件不建立索引
+)
+
+// IndexChunk 是索引中的一段文件内容
+type IndexChunk struct {
+	Path      string    `json:"path"`
+	StartLine int       `json:"start_line"` // 从 1 开始
+	EndLine   int       `json:"end_line"`
+	Text      string    `json:"text"`
+	Vector    []float32 `json:"vector"`
+}
+
+// SearchHit 是一条检索结果
+type SearchHit struct {
+	IndexChunk
+	Score float64 // 余弦相似度
+}
+
+// indexData 是索引文件的格式
+type indexData struct {
+	Model      string            `json:"model"`
+	Dimensions int               `json:"dimensions"`
+	Files      map[string]string `json:"files"` // 路径 → 内容的 SHA-256
+	Chunks     []IndexChunk      `json:"chunks"`
+}
+
+// VectorIndex 是项目文件的本地向量索引。
+// 按文件内容的校验和增量更新，只为新增或修改的文件请求向量，结果保存在磁盘上
+type VectorIndex struct {
+	path     string
+	embedder types.Embedder
+	data     indexData
+}
+
+// IndexPath 返回项目目录与向量模型对应的索引文件，位于用户缓存目录
+func IndexPath(root, model string) string {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(root + "\x00" + model))
+	return filepath.Join(dir, "akashaterminal", "index", hex.EncodeToString(sum[:8])+".json")
+}
+
+// NewVectorIndex 打开索引文件；文件不存在或向量模型不同时从空索引开始
+func NewVectorIndex(path string, embedder types.Embedder) (*VectorIndex, error) {
+	info := embedder.EmbeddingInfo()
+	vi := &VectorIndex{
+		path:     path,
+		embedder: embedder,
+		data:     indexData{Model: info.Model, Dimensions: info.Dimensions, Files: make(map[string]string)},
+	}
+	
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return vi, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	var stored indexData
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("load index %s: %v", path, err)
+	}
+	if stored.Model == info.Model && (info.Dimensions == 0 || stored.Dimensions == info.Dimensions) && stored.Files != nil {
+		vi.data = stored
+	}
+	return vi, nil
+}
+
+// Update 使索引与 root 下的文件一致，paths 为相对 root 的路径。
+// 返回重新建立索引的文件数
+func (vi *VectorIndex) Update(ctx context.Context, root string, paths []string) (int, error) {
+	current := make(map[string]string, len(paths))
+	var changed []string
+	var pending []IndexChunk
+	
+	for _, path := range paths {
+		content, ok := readIndexable(filepath.Join(root, path))
+		if !ok {
+			continue
+		}
+		sum := sha256.Sum256(content)
+		checksum := hex.EncodeToString(sum[:])
+		current[path] = checksum
+		if vi.data.Files[path] == checksum {
+			continue
+		}
+		changed = append(changed, path)
+		pending = append(pending, splitChunks(path, string(content))...)
+	}
+	
+	removed := 0
+	for path := range vi.data.Files {
+		if _, ok := current[path]; !ok {
+			removed++
+		}
+	}
+	if len(changed) == 0 && removed == 0 {
+		return 0, nil
+	}
+	
+	// 向量包含文件路径，便于按文件名检索
+	texts := make([]string, len(pending))
+	for i, chunk := range pending {
+		texts[i] = chunk.Path + "\n" + chunk.Text
+	}
+	if len(texts) > 0 {
+		vectors, err := vi.embedder.Embed(ctx, texts)
+		if err != nil {
+			return 0, err
+		}
+		for i := range pending {
+			pending[i].Vector = vectors[i]
+		}
+		if vi.data.Dimensions == 0 && len(vectors) > 0 {
+			vi.data.Dimensions = len(vectors[0])
+		}
+	}
+	
+	// 保留未变化文件的块，替换修改过的文件，丢弃已删除的文件
+	kept := pending
+	for _, chunk := range vi.data.Chunks {
+		if checksum, ok := current[chunk.Path]; ok && vi.data.Files[chunk.Path] == checksum {
+			kept = append(kept, chunk)
+		}
+	}
+	vi.data.Chunks = kept
+	vi.data.Files = current
+	
+	return len(changed), vi.save()
+}
+
+// Search 返回与 query 最相关的 k 个片段，按相似度从高到低排列
+func (vi *VectorIndex) Search(ctx context.Context, query string, k int) ([]SearchHit, error) {
+	if len(vi.data.Chunks) == 0 || k <= 0 {
+		return nil, nil
+	}
+	
+	vectors, err := vi.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	
+	hits := make([]SearchHit, 0, len(vi.data.Chunks))
+	for _, chunk := range vi.data.Chunks {
+		hits = append(hits, SearchHit{IndexChunk: chunk, Score: cosine(vectors[0], chunk.Vector)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// Len 返回索引中的文件数
+func (vi *VectorIndex) Len() int {
+	return len(vi.data.Files)
+}
+
+// save 写入索引文件，先写临时文件再替换
+func (vi *VectorIndex) save() error {
+	data, err := json.Marshal(vi.data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(vi.path), 0700); err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(vi.path, data, 0600)
+}
+
+// readIndexable 读取适合建立索引的文本文件，跳过过大的文件与二进制文件
+func readIndexable(path string) ([]byte, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > indexMaxFileSize {
+		return nil, false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(content, 0) >= 0 || len(bytes.TrimSpace(content)) == 0 {
+		return nil, false
+	}
+	return content, true
+}
+
+// splitChunks 将文件内容按行切块，超过 chunkMaxBytes 的单行（如压缩后的代码）单独按字节切成多块
+func splitChunks(path, content string) []IndexChunk {
+	lines := strings.Split(content, "\n")
+	
+	var chunks []IndexChunk
+	start := 0
+	size := 0
+	flush := func(end int) {
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) != "" {
+			chunks = append(chunks, IndexChunk{Path: path, StartLine: start + 1, EndLine: end, Text: text})
+		}
+		start, size = end, 0
+	}
+	
+	for i, line := range lines {
+		if len(line) > chunkMaxBytes {
+			if i > start {
+				flush(i)
+			}
+			for _, piece := range splitLongLine(line, chunkMaxBytes) {
+				if strings.TrimSpace(piece) != "" {
+					chunks = append(chunks, IndexChunk{Path: path, StartLine: i + 1, EndLine: i + 1, Text: piece})
+				}
+			}
+			start, size = i+1, 0
+			continue
+		}
+		if i > start && (i-start >= chunkLines || size+len(line) > chunkMaxBytes) {
+			flush(i)
+		}
+		size += len(line) + 1
+	}
+	if start < len(lines) {
+		flush(len(lines))
+	}
+	return chunks
+}
+
+// splitLongLine 将一行按不超过 max 字节切分，不拆开多字节字符
+func splitLongLine(line string, max int) []string {
+	var pieces []string
+	for len(line) > max {
+		cut := max
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		pieces = append(pieces, line[:cut])
+		line = line[cut:]
+	}
+	return append(pieces, line)
+}
+
+// cosine 计算两个向量的余弦相似度，维度不同或为零向量时返回 0
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
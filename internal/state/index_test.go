@@ -0,0 +1,185 @@
+package state
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// fakeEmbedder 记录收到的文本，向量的第一维为文本长度
+type fakeEmbedder struct {
+	texts []string
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.texts = append(e.texts, texts...)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), 1}
+	}
+	return vectors, nil
+}
+
+func (e *fakeEmbedder) EmbeddingInfo() types.EmbeddingInfo {
+	return types.EmbeddingInfo{Model: "fake-embedding", Dimensions: 2}
+}
+
+func (e *fakeEmbedder) SendRequest(ctx context.Context, messages []types.Message) (types.Response, error) {
+	return types.Response{}, nil
+}
+
+func (e *fakeEmbedder) GetName() string                       { return "fake" }
+func (e *fakeEmbedder) GetModel() string                      { return "fake" }
+func (e *fakeEmbedder) SupportsFeature(types.Capability) bool { return false }
+
+func TestVectorIndexUpdate(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, content string) {
+		if err := os.WriteFile(filepath.Join(root, path), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.go", "package a\n")
+	write("b.go", "package b\n")
+	write("empty.txt", "  \n")
+	write("bin.dat", "x\x00y")
+	
+	indexPath := filepath.Join(t.TempDir(), "index.json")
+	embedder := &fakeEmbedder{}
+	vi, err := NewVectorIndex(indexPath, embedder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	// 依次执行，每步之前修改文件
+	tests := []struct {
+		name     string
+		before   func()
+		paths    []string
+		updated  int      // Update 返回的重新索引文件数
+		embedded []string // 本步请求向量的文件
+		files    []string // 更新后索引中的文件
+	}{
+		{"initial", nil, []string{"a.go", "b.go", "empty.txt", "bin.dat"}, 2, []string{"a.go", "b.go"}, []string{"a.go", "b.go"}},
+		{"unchanged", nil, []string{"a.go", "b.go"}, 0, nil, []string{"a.go", "b.go"}},
+		{"changed", func() { write("b.go", "package b\n\nfunc B() {}\n") }, []string{"a.go", "b.go"}, 1, []string{"b.go"}, []string{"a.go", "b.go"}},
+		{"removed", nil, []string{"b.go"}, 0, nil, []string{"b.go"}},
+	}
+	for _, tt := range tests {
+		if tt.before != nil {
+			tt.before()
+		}
+		embedder.texts = nil
+		updated, err := vi.Update(context.Background(), root, tt.paths)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		
+		var embedded []string
+		for _, text := range embedder.texts {
+			embedded = append(embedded, strings.SplitN(text, "\n", 2)[0])
+		}
+		var files []string
+		for path := range vi.data.Files {
+			files = append(files, path)
+		}
+		sort.Strings(files)
+		var chunkFiles []string
+		for _, chunk := range vi.data.Chunks {
+			chunkFiles = append(chunkFiles, chunk.Path)
+		}
+		sort.Strings(chunkFiles)
+		
+		if updated != tt.updated || strings.Join(embedded, ",") != strings.Join(tt.embedded, ",") {
+			t.Errorf("%s: updated %d, embedded %v; want %d, %v", tt.name, updated, embedded, tt.updated, tt.embedded)
+		}
+		if strings.Join(files, ",") != strings.Join(tt.files, ",") || strings.Join(chunkFiles, ",") != strings.Join(tt.files, ",") {
+			t.Errorf("%s: files %v with chunks from %v, want %v", tt.name, files, chunkFiles, tt.files)
+		}
+	}
+	
+	// 重新打开时从磁盘读取索引，未变化的文件不再请求向量
+	embedder.texts = nil
+	reopened, err := NewVectorIndex(indexPath, embedder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated, err := reopened.Update(context.Background(), root, []string{"b.go"}); err != nil || updated != 0 || len(embedder.texts) != 0 {
+		t.Errorf("reopened index updated %d files, embedded %d texts, err %v", updated, len(embedder.texts), err)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	line := func(n int, s string) string { return strings.Repeat(s, n) }
+	many := make([]string, chunkLines+5)
+	for i := range many {
+		many[i] = "line"
+	}
+	
+	type span struct {
+		start, end, size int
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    []span
+	}{
+		{"small file", "a\nb\nc", []span{{1, 3, 5}}},
+		{"blank file", "\n  \n", nil},
+		{"line limit", strings.Join(many, "\n"), []span{{1, chunkLines, chunkLines*5 - 1}, {chunkLines + 1, chunkLines + 5, 24}}},
+		{"byte limit", line(1200, "a") + "\n" + line(1200, "b"), []span{{1, 1, 1200}, {2, 2, 1200}}},
+		{"long line is hard split", "x\n" + line(chunkMaxBytes*2+10, "z") + "\ny", []span{
+			{1, 1, 1}, {2, 2, chunkMaxBytes}, {2, 2, chunkMaxBytes}, {2, 2, 10}, {3, 3, 1},
+		}},
+		// 多字节字符不被拆开
+		{"long line with runes", line(chunkMaxBytes/3+1, "汉"), []span{{1, 1, chunkMaxBytes / 3 * 3}, {1, 1, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitChunks("f.txt", tt.content)
+			var got []span
+			for _, c := range chunks {
+				got = append(got, span{c.StartLine, c.EndLine, len(c.Text)})
+				if len(c.Text) > chunkMaxBytes || !utf8.ValidString(c.Text) || c.Path != "f.txt" {
+					t.Errorf("invalid chunk %d-%d (%d bytes)", c.StartLine, c.EndLine, len(c.Text))
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("chunks = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("chunks = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		a, b []float32
+		want float64
+	}{
+		{[]float32{1, 0}, []float32{1, 0}, 1},
+		{[]float32{1, 0}, []float32{0, 1}, 0},
+		{[]float32{1, 1}, []float32{-1, -1}, -1},
+		{[]float32{3, 4}, []float32{6, 8}, 1},
+		{[]float32{1, 2}, []float32{2, 1}, 0.8},
+		{[]float32{0, 0}, []float32{1, 1}, 0},
+		{[]float32{1, 2, 3}, []float32{1, 2}, 0},
+	}
+	for _, tt := range tests {
+		if got := cosine(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("cosine(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
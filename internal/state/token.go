@@ -1,17 +1,28 @@
 package state
 
 import (
-	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	
 	"github.com/yantianyv/AkashaTerminal/pkg/types"
 )
 
 // TokenEstimator 估算文本的 token 数量
-type TokenEstimator struct{}
+type TokenEstimator struct {
+	ratio float64 // 实际 token 数与字符估算之比，0 表示尚未校准
+}
 
 func (te *TokenEstimator) Estimate(text string) int {
+	estimate := te.raw(text)
+	if te.ratio == 0 {
+		return estimate
+	}
+	return int(float64(estimate) * te.ratio)
+}
+
+// raw 是未经校准的字符估算
+func (te *TokenEstimator) raw(text string) int {
 	// 简易估算：4个英文字符 ≈ 1 token，1个汉字 ≈ 2 tokens
 	total := 0
 	for _, r := range text {
@@ -24,11 +35,31 @@ func (te *TokenEstimator) Estimate(text string) int {
 	return total / 4
 }
 
+// calibrate 根据供应商报告的实际 token 数修正估算比例，
+// 与旧比例取平均以平滑单次请求的偏差
+func (te *TokenEstimator) calibrate(actual, estimated int) {
+	if actual <= 0 || estimated <= 0 {
+		return
+	}
+	
+	ratio := float64(actual) / float64(estimated)
+	ratio = math.Max(0.25, math.Min(4, ratio))
+	if te.ratio == 0 {
+		te.ratio = ratio
+	} else {
+		te.ratio = (te.ratio + ratio) / 2
+	}
+}
+
 // TokenManager 管理 token 使用情况
 type TokenManager struct {
 	maxTokens      int
 	currentToken   int
 	history        []*ConversationRecord
+	tokenEstimator TokenEstimator
+	nextID         int
+	lastUsage      types.Usage // 最近一次请求报告的用量
+	reasoning      int         // 会话累计的思考 token，不占用上下文
 }
 
 type ConversationRecord struct {
@@ -46,17 +77,16 @@ func NewTokenManager(maxTokens int) *TokenManager {
 	}
 }
 
+// AddRecord 将一条消息追加到对话历史，并在超出阈值时清理旧记录。
+// 操作的内容不随 Messages 发送，只按消息文本计入 token
 func (tm *TokenManager) AddRecord(record *ConversationRecord) error {
-	estimator := TokenEstimator{}
-	
-	// 估算 token 使用
-	tokens := estimator.Estimate(record.Content)
-	if record.Operation.Content != "" {
-		tokens += estimator.Estimate(record.Operation.Content)
-	}
+	tokens := tm.tokenEstimator.Estimate(record.Content)
 	
+	tm.nextID++
+	record.ID = tm.nextID
 	record.TokenCount = tokens
 	tm.currentToken += tokens
+	tm.history = append(tm.history, record)
 	
 	// 应用清理策略
 	return tm.applyCleanupStrategy()
@@ -84,7 +114,6 @@ func (tm *TokenManager) applyCleanupStrategy() error {
 func (tm *TokenManager) level1Cleanup() {
 	// 保留最近2条记录
 	keep := min(2, len(tm.history))
-	preserved := tm.history[len(tm.history)-keep:]
 	
 	// 清理3轮前的记录
 	for i := 0; i < len(tm.history)-keep && tm.currentToken > tm.maxTokens/2; i++ {
@@ -92,12 +121,9 @@ func (tm *TokenManager) level1Cleanup() {
 		
 		// 清理写入操作详情
 		if rec.Operation.Action == "write" && rec.Operation.Content != "" {
-			tokenSave := tm.tokenEstimator.Estimate(rec.Operation.Content)
 			rec.Operation.Content = ""
 			rec.Operation.OldText = ""
-			rec.Operation = fmt.Sprintf("已清理的写入操作: %s", rec.Operation.Path)
-			rec.TokenCount -= tokenSave
-			tm.currentToken -= tokenSave
+			tm.replaceContent(rec, fmt.Sprintf("已清理的写入操作: %s", rec.Operation.Path))
 		}
 		
 		// 标记为可清理
@@ -123,11 +149,8 @@ func (tm *TokenManager) level2Cleanup() {
 	// 清理非关键文件读取内容
 	for i, rec := range tm.history {
 		if i > 0 && rec.Operation.Action == "read" && tm.currentToken > tm.maxTokens/2 {
-			tokenSave := tm.tokenEstimator.Estimate(rec.Operation.Content)
 			rec.Operation.Content = ""
-			rec.Operation = fmt.Sprintf("已清理的文件读取记录: %s", rec.Operation.Path)
-			rec.TokenCount -= tokenSave
-			tm.currentToken -= tokenSave
+			tm.replaceContent(rec, fmt.Sprintf("已清理的文件读取记录: %s", rec.Operation.Path))
 		}
 	}
 	
@@ -135,16 +158,21 @@ func (tm *TokenManager) level2Cleanup() {
 	for i, rec := range tm.history {
 		if i > 0 && rec != nil && tm.currentToken > tm.maxTokens*3/5 {
 			if !strings.HasPrefix(rec.Content, "[关键]") {
-				tokenSave := rec.TokenCount - 10 // 保留基本信息
-				rec.Content = fmt.Sprintf("[精简] 记录 #%d (%s)", rec.ID, rec.Operation.Action)
+				tm.replaceContent(rec, fmt.Sprintf("[精简] 记录 #%d (%s)", rec.ID, rec.Operation.Action))
 				rec.Operation = types.FileOperation{}
-				rec.TokenCount = 10
-				tm.currentToken -= tokenSave
 			}
 		}
 	}
 }
 
+// replaceContent 替换记录的消息文本，并按新文本更新 token 计数
+func (tm *TokenManager) replaceContent(rec *ConversationRecord, content string) {
+	tokens := tm.tokenEstimator.Estimate(content)
+	tm.currentToken += tokens - rec.TokenCount
+	rec.Content = content
+	rec.TokenCount = tokens
+}
+
 func (tm *TokenManager) checkCleanupEffectiveness() error {
 	if tm.currentToken > tm.maxTokens {
 		return fmt.Errorf("清理后仍超过100%% Token限制 (%d/%d)", tm.currentToken, tm.maxTokens)
@@ -161,6 +189,83 @@ func (tm *TokenManager) checkCleanupEffectiveness() error {
 	return nil
 }
 
+// Messages 返回历史记录对应的对话消息，作为下一轮请求的上下文。
+// 同一轮中执行操作的记录与回复角色相同，连续的同角色记录合并为一条消息
+func (tm *TokenManager) Messages() []types.Message {
+	messages := make([]types.Message, 0, len(tm.history))
+	for _, rec := range tm.history {
+		if rec == nil || rec.Content == "" {
+			continue
+		}
+		if n := len(messages); n > 0 && messages[n-1].Role == rec.Role {
+			messages[n-1].Content += "\n" + rec.Content
+			continue
+		}
+		messages = append(messages, types.Message{
+			Role:    rec.Role,
+			Content: rec.Content,
+		})
+	}
+	return messages
+}
+
+// Reconcile 用供应商报告的实际用量校准估算：实际值与估算值之比用于修正后续记录的估算。
+// 系统消息包含项目结构与文件内容，每轮重新生成，清理历史无法减少这部分，
+// 因此当前占用只按对话历史（含本轮输入与回复）在估算中所占的比例计入。
+// 思考过程不会回传给模型，其 token 单独累计，不计入上下文。
+// prompt 与 reply 是本轮发送的对话和收到的回复，供应商未报告用量或用量为估算值时保持本地估算
+func (tm *TokenManager) Reconcile(usage types.Usage, prompt []types.Message, reply string) error {
+	tm.lastUsage = usage
+	tm.reasoning += usage.ReasoningTokens
+	
+	// 估算的用量与本地估算同源，不能用于校准
+	actual := usage.PromptTokens + usage.CompletionTokens - usage.ReasoningTokens
+	if actual <= 0 || usage.Estimated {
+		return nil
+	}
+	
+	estimated := tm.tokenEstimator.raw(reply)
+	system := 0
+	for _, msg := range prompt {
+		tokens := tm.tokenEstimator.raw(msg.Content)
+		estimated += tokens
+		if msg.Role == types.RoleSystem {
+			system += tokens
+		}
+	}
+	tm.tokenEstimator.calibrate(actual, estimated)
+	if estimated == 0 {
+		return nil
+	}
+	
+	tm.currentToken = actual * (estimated - system) / estimated
+	return tm.applyCleanupStrategy()
+}
+
+// EstimateUsage 在供应商未报告用量时按发送的对话与收到的回复估算，用于计费并标记为估算值；
+// 已报告用量时原样返回
+func (tm *TokenManager) EstimateUsage(usage types.Usage, prompt []types.Message, reply string) types.Usage {
+	if usage.PromptTokens > 0 || usage.CompletionTokens > 0 {
+		return usage
+	}
+	estimated := types.Usage{CompletionTokens: tm.tokenEstimator.Estimate(reply), Estimated: true}
+	for _, msg := range prompt {
+		estimated.PromptTokens += tm.tokenEstimator.Estimate(msg.Content)
+	}
+	estimated.TotalTokens = estimated.PromptTokens + estimated.CompletionTokens
+	return estimated
+}
+
+// LastUsage 返回最近一次请求报告的用量，未报告时为零值
+func (tm *TokenManager) LastUsage() types.Usage {
+	return tm.lastUsage
+}
+
+// ReasoningTokens 返回会话累计的思考 token
+func (tm *TokenManager) ReasoningTokens() int {
+	return tm.reasoning
+}
+
 func (tm *TokenManager) GetTokenUsage() (int, int) {
 	return tm.currentToken, tm.maxTokens
 }
@@ -0,0 +1,80 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	
+	"github.com/yantianyv/AkashaTerminal/pkg/types"
+)
+
+// 系统消息中的项目内容远超上限的一半时，不应因此清理对话历史
+func TestReconcileExcludesSystemPrompt(t *testing.T) {
+	tm := NewTokenManager(8192)
+	for _, rec := range []*ConversationRecord{
+		{Role: types.RoleUser, Content: "first question"},
+		{Role: types.RoleAssistant, Content: "first answer"},
+		{Role: types.RoleUser, Content: "second question"},
+		{Role: types.RoleAssistant, Content: "second answer"},
+	} {
+		if err := tm.AddRecord(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	
+	system := strings.Repeat("x", 24000) // 约 6000 token
+	prompt := append([]types.Message{{Role: types.RoleSystem, Content: system}}, tm.Messages()...)
+	usage := types.Usage{PromptTokens: 6020, CompletionTokens: 5}
+	
+	if err := tm.Reconcile(usage, prompt, "second answer"); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	current, _ := tm.GetTokenUsage()
+	if current <= 0 || current > 100 {
+		t.Errorf("history tokens = %d, want only the conversation share", current)
+	}
+	for _, msg := range tm.Messages() {
+		if strings.HasPrefix(msg.Content, "[精简]") {
+			t.Errorf("history was compacted: %q", msg.Content)
+		}
+	}
+}
+
+// 计入的 token 只包含 Messages 实际发送的内容，连续的同角色记录合并为一条消息
+func TestMessagesMatchRecordedTokens(t *testing.T) {
+	tm := NewTokenManager(100000)
+	records := []*ConversationRecord{
+		{Role: types.RoleUser, Content: "修改 main.go"},
+		{Role: types.RoleAssistant, Content: "已执行操作: read main.go", Operation: types.FileOperation{Action: "read", Path: "main.go", Content: strings.Repeat("x", 4000)}},
+		{Role: types.RoleAssistant, Content: "已执行操作: write main.go", Operation: types.FileOperation{Action: "write", Path: "main.go", Content: strings.Repeat("y", 4000)}},
+		{Role: types.RoleAssistant, Content: "完成"},
+		{Role: types.RoleUser, Content: "谢谢"},
+		{Role: types.RoleAssistant, Content: ""}, // 空回复不发送
+	}
+	for _, rec := range records {
+		if err := tm.AddRecord(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	
+	messages := tm.Messages()
+	wantRoles := []string{types.RoleUser, types.RoleAssistant, types.RoleUser}
+	if len(messages) != len(wantRoles) {
+		t.Fatalf("messages = %+v, want roles %v", messages, wantRoles)
+	}
+	estimated := 0
+	for i, msg := range messages {
+		if msg.Role != wantRoles[i] {
+			t.Errorf("message %d role = %s, want %s", i, msg.Role, wantRoles[i])
+		}
+		estimated += tm.tokenEstimator.Estimate(msg.Content)
+	}
+	if want := "已执行操作: read main.go\n已执行操作: write main.go\n完成"; messages[1].Content != want {
+		t.Errorf("merged assistant message = %q, want %q", messages[1].Content, want)
+	}
+	
+	// 各条记录分别取整，合并时还加入了换行，合并后的估算可能略大
+	current, _ := tm.GetTokenUsage()
+	if current < estimated-len(records) || current > estimated {
+		t.Errorf("recorded %d tokens, messages estimate %d", current, estimated)
+	}
+}
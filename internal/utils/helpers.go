@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 	
 	"github.com/fatih/color"
 	"github.com/yantianyv/AkashaTerminal/pkg/types"
@@ -67,6 +68,41 @@ func DisplayTokenUsage(current, max int) {
 		colorStatus(status), percentage, formatTokenCount(current), formatTokenCount(max))
 }
 
+// DisplayRequestUsage 显示供应商报告的本次请求用量，未报告时不显示，按字符估算的用量注明估算。
+// cached 表示回复来自本地响应缓存，用量为缓存时记录的值
+func DisplayRequestUsage(usage types.Usage, cached bool) {
+	suffix := ""
+	if usage.Estimated {
+		suffix = "（估算）"
+	}
+	if cached {
+		suffix += color.GreenString(" · 缓存命中")
+	}
+	if usage.PromptTokens == 0 && usage.CompletionTokens == 0 {
+		if cached {
+			color.HiBlack("本次请求: 缓存命中")
+		}
+		return
+	}
+	if usage.ReasoningTokens > 0 {
+		color.HiBlack("本次请求: 输入 %s · 输出 %s tokens（其中思考 %s）%s",
+			formatTokenCount(usage.PromptTokens), formatTokenCount(usage.CompletionTokens),
+			formatTokenCount(usage.ReasoningTokens), suffix)
+		return
+	}
+	color.HiBlack("本次请求: 输入 %s · 输出 %s tokens%s",
+		formatTokenCount(usage.PromptTokens), formatTokenCount(usage.CompletionTokens), suffix)
+}
+
+// DisplayCost 显示本次请求的费用以及会话与历史累计花费，request 为空表示未配置该模型的价格
+func DisplayCost(request, session, total string) {
+	if request == "" {
+		color.HiBlack("费用: 未配置该模型的价格 · 本次会话 %s · 累计 %s", session, total)
+		return
+	}
+	color.HiBlack("费用: 本次 %s · 本次会话 %s · 累计 %s", request, session, total)
+}
+
 func formatTokenCount(count int) string {
 	if count > 1000 {
 		return fmt.Sprintf("%.1fk", float64(count)/1000)
@@ -91,3 +127,8 @@ func ShowSuccess(message string) {
 func ShowWarning(message string) {
 	color.Yellow("\n⚠️ 注意: %s", message)
 }
+
+// ShowRetry 显示供应商请求的重试状态
+func ShowRetry(attempt, maxRetries int, reason string, wait time.Duration) {
+	color.Yellow("\n🔄 重试 %d/%d: %s，%.1f秒后重试", attempt, maxRetries, reason, wait.Seconds())
+}
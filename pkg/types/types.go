@@ -1,5 +1,10 @@
 package types
 
+import (
+	"context"
+	"encoding/base64"
+)
+
 // FileState 表示文件的当前状态
 type FileState struct {
 	Path     string `json:"path"`
@@ -27,6 +32,191 @@ type FileOperation struct {
 	Offset   int    `json:"offset,omitempty"`
 }
 
+// 消息角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message 是与供应商无关的对话消息
+type Message struct {
+	Role       string     `json:"role"` // system/user/assistant/tool
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant 消息请求的工具调用
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool 消息对应的调用 ID
+	Images     []Image    `json:"images,omitempty"`       // user 消息附带的图片，需要供应商支持 CapMultimodal
+}
+
+// Image 是随消息发送的图片
+type Image struct {
+	MediaType string `json:"media_type"` // 如 image/png
+	Data      []byte `json:"data"`
+}
+
+// DataURL 返回 base64 编码的 data URL
+func (img Image) DataURL() string {
+	return "data:" + img.MediaType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// Tool 描述一个可供模型调用的工具
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"` // JSON Schema
+}
+
+// ToolCall 是模型返回的一次工具调用
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON 编码的参数
+}
+
+// Usage 是供应商报告的 token 用量，未报告时为零值或标记为估算的值
+type Usage struct {
+	PromptTokens     int  `json:"prompt_tokens"`
+	CompletionTokens int  `json:"completion_tokens"`
+	TotalTokens      int  `json:"total_tokens"`
+	ReasoningTokens  int  `json:"reasoning_tokens,omitempty"` // 推理模型的思考 token，已计入 CompletionTokens
+	CachedTokens     int  `json:"cached_tokens,omitempty"`    // 命中缓存的输入 token，已计入 PromptTokens
+	Estimated        bool `json:"estimated,omitempty"`        // 供应商未报告用量，以上数值按字符估算
+}
+
+// Add 返回两次用量之和
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+		ReasoningTokens:  u.ReasoningTokens + other.ReasoningTokens,
+		CachedTokens:     u.CachedTokens + other.CachedTokens,
+		Estimated:        u.Estimated || other.Estimated,
+	}
+}
+
+// CacheConfig 是本地响应缓存的设置，对所有配置生效
+type CacheConfig struct {
+	Enabled   bool `json:"enabled"`
+	TTLHours  int  `json:"ttl_hours,omitempty"`   // 缓存有效期（小时），0 使用默认值
+	MaxSizeMB int  `json:"max_size_mb,omitempty"` // 缓存目录大小上限（MB），0 使用默认值
+}
+
+// ModelPrice 是模型每百万 token 的价格
+type ModelPrice struct {
+	Input       float64 `json:"input"`                  // 未命中缓存的输入
+	CachedInput float64 `json:"cached_input,omitempty"` // 命中缓存的输入，0 表示与 Input 相同
+	Output      float64 `json:"output"`                 // 输出，包括思考过程
+	Currency    string  `json:"currency"`               // 币种，如 CNY、USD
+}
+
+// Response 是一次请求的结果
+type Response struct {
+	Content      string
+	Reasoning    string     // 推理模型的思考过程，不属于回复，也不应回传给模型
+	ToolCalls    []ToolCall // 仅 SendWithTools 可能返回
+	FinishReason string     // stop/length/tool_calls/content_filter 等
+	Model        string     // 供应商实际使用的模型 ID，未返回时为配置的模型
+	Usage        Usage
+}
+
+// Message 将回复转换为可追加到对话中的 assistant 消息
+func (r Response) Message() Message {
+	return Message{
+		Role:      RoleAssistant,
+		Content:   r.Content,
+		ToolCalls: r.ToolCalls,
+	}
+}
+
+// Capability 是供应商或模型支持的能力
+type Capability string
+
+const (
+	CapLongContext  Capability = "long_context" // 长上下文（64k 及以上）
+	CapMultimodal   Capability = "multimodal"   // 图像输入
+	CapTools        Capability = "tools"        // 原生工具调用
+	CapQuantization Capability = "quantization" // 量化推理
+	CapEnterprise   Capability = "enterprise"   // 企业级部署
+	CapCustomModel  Capability = "custom_model" // 自定义或微调模型
+	CapReasoning    Capability = "reasoning"    // 回复前输出独立的思考过程
+	
+	// CapStructuredOutput 表示请求可通过 response_format 约束回复为 JSON 对象，
+	// 见 ResponseFormat
+	CapStructuredOutput Capability = "structured_output"
+)
+
+// ResponseFormat 要求模型以符合 Schema 的 JSON 对象回复。
+// 支持 json_schema 的供应商按 Schema 严格约束；只支持 JSON 模式的供应商仅保证回复是 JSON 对象，
+// 具体格式需在提示词中说明
+type ResponseFormat struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"` // JSON Schema，顶层须为 object
+}
+
+// ModelInfo 描述供应商提供的一个模型
+type ModelInfo struct {
+	ID            string       `json:"id"`
+	ContextWindow int          `json:"context_window,omitempty"` // 上下文窗口（token），未知时为 0
+	Capabilities  []Capability `json:"capabilities,omitempty"`
+}
+
+// Supports 报告模型是否具备指定能力
+func (m ModelInfo) Supports(capability Capability) bool {
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerationParams 是可选的生成参数，nil 或空值表示使用供应商默认值。
+// 各供应商只发送其接口支持的参数
+type GenerationParams struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+}
+
+// Merge 返回以 override 中已设置的参数覆盖后的结果
+func (g GenerationParams) Merge(override GenerationParams) GenerationParams {
+	if override.Temperature != nil {
+		g.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		g.TopP = override.TopP
+	}
+	if override.Stop != nil {
+		g.Stop = override.Stop
+	}
+	if override.Seed != nil {
+		g.Seed = override.Seed
+	}
+	if override.PresencePenalty != nil {
+		g.PresencePenalty = override.PresencePenalty
+	}
+	if override.FrequencyPenalty != nil {
+		g.FrequencyPenalty = override.FrequencyPenalty
+	}
+	return g
+}
+
+// TransportConfig 是供应商 HTTP 连接的网络设置
+type TransportConfig struct {
+	Proxy              string `json:"proxy,omitempty"`                // 代理地址；为空时读取 HTTPS_PROXY 等环境变量，direct 表示直连
+	CAFile             string `json:"ca_file,omitempty"`              // 额外信任的根证书 (PEM)，与系统证书一起使用
+	ClientCert         string `json:"client_cert,omitempty"`          // mTLS 客户端证书 (PEM)
+	ClientKey          string `json:"client_key,omitempty"`           // mTLS 客户端私钥 (PEM)
+	TLSMinVersion      string `json:"tls_min_version,omitempty"`      // 最低 TLS 版本: 1.0/1.1/1.2/1.3
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"` // 跳过证书校验，仅用于本地测试
+}
+
 // APIConfig 表示 API 配置
 type APIConfig struct {
 	Name       string  `json:"-"`
@@ -41,12 +231,100 @@ type APIConfig struct {
 	AgentID    string  `json:"agent_id,omitempty"`
 	AuthType   string  `json:"auth_type,omitempty"`
 	AuthKey    string  `json:"auth_key,omitempty"`
+	Timeout    int     `json:"timeout,omitempty"` // 等待响应头及流式响应中两次收到数据的最长间隔（秒），0 表示使用默认值
+	
+	// 生成参数直接写在配置中，如 "temperature": 0
+	GenerationParams
+
+	// 重试策略：仅对 429/5xx 及未发出的连接错误重试
+	MaxRetries    int `json:"max_retries,omitempty"`        // 最大重试次数，0 使用默认值，负数关闭重试
+	RetryDelay    int `json:"retry_delay_ms,omitempty"`     // 首次退避时间（毫秒）
+	RetryMaxDelay int `json:"retry_max_delay_ms,omitempty"` // 退避时间上限（毫秒）
+
+	// 客户端限流：使用同一密钥的请求共享额度，包括本机的其他 akasha 进程；0 表示不限制
+	RPM int `json:"rpm,omitempty"` // 每分钟请求数
+	TPM int `json:"tpm,omitempty"` // 每分钟 token 数，发送前按请求体估算，收到响应后按实际用量修正
+
+	// Transport 是代理、证书等网络设置，对所有供应商生效
+	Transport TransportConfig `json:"transport,omitempty"`
+
+	// 向量模型，用于项目文件索引；为空时使用供应商的默认模型
+	EmbeddingModel      string `json:"embedding_model,omitempty"`
+	EmbeddingDimensions int    `json:"embedding_dimensions,omitempty"` // 请求的向量维度，仅部分模型支持
+
+	// Fallbacks 是主供应商失败时依次尝试的其他配置名
+	Fallbacks []string `json:"fallbacks,omitempty"`
+
+	// 阿里云 AccessKey，仅用于 bailian 供应商的 aksk 认证
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	AccessKeySecret string `json:"access_key_secret,omitempty"`
+
+	// 以下字段仅用于 custom 供应商
+	RequestTemplate string            `json:"request_template,omitempty"` // 请求体模板 (text/template)
+	ResponsePath    string            `json:"response_path,omitempty"`    // 回复文本的 JSONPath，如 $.choices[0].message.content
+	Headers         map[string]string `json:"headers,omitempty"`          // 额外请求头，值支持模板
+	
+	// Script 是 mock 供应商读取的脚本文件路径
+	Script string `json:"script,omitempty"`
 }
 
 // AIProvider 是所有供应商实现的接口
 type AIProvider interface {
-	SendRequest(prompt string, state interface{}) (string, error)
+	// SendRequest 发送完整对话并返回回复，ctx 取消时请求随之中止
+	SendRequest(ctx context.Context, messages []Message) (Response, error)
 	GetName() string
 	GetModel() string
-	SupportsFeature(feature string) bool
+	SupportsFeature(feature Capability) bool
+}
+
+// StreamingProvider 是支持流式输出的供应商
+type StreamingProvider interface {
+	AIProvider
+	// StreamRequest 每收到一段文本调用一次 onChunk，结束后返回完整回复
+	StreamRequest(ctx context.Context, messages []Message, onChunk func(string)) (Response, error)
+}
+
+// ToolCallingProvider 是支持原生工具调用的供应商，
+// 是否启用由 SupportsFeature(CapTools) 决定
+type ToolCallingProvider interface {
+	AIProvider
+	// SendWithTools 发送对话与工具定义，返回的回复可能包含 ToolCalls
+	SendWithTools(ctx context.Context, messages []Message, tools []Tool) (Response, error)
+}
+
+// StreamingToolProvider 是可以在工具调用时流式输出文本的供应商
+type StreamingToolProvider interface {
+	ToolCallingProvider
+	// StreamWithTools 与 SendWithTools 相同，回复中的文本每收到一段调用一次 onChunk
+	StreamWithTools(ctx context.Context, messages []Message, tools []Tool, onChunk func(string)) (Response, error)
+}
+
+// StructuredOutputProvider 是支持结构化输出的供应商，
+// 是否启用由 SupportsFeature(CapStructuredOutput) 决定
+type StructuredOutputProvider interface {
+	AIProvider
+	// SendStructured 要求回复为符合 format 的 JSON 对象，onChunk 不为 nil 时以流式方式发送
+	SendStructured(ctx context.Context, messages []Message, format ResponseFormat, onChunk func(string)) (Response, error)
+}
+
+// ModelLister 是可以列出可用模型的供应商
+type ModelLister interface {
+	AIProvider
+	// ListModels 返回供应商当前提供的模型及其上下文窗口与能力
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// EmbeddingInfo 描述供应商使用的向量模型
+type EmbeddingInfo struct {
+	Model      string // 向量模型
+	Dimensions int    // 向量维度，未知时为 0
+}
+
+// Embedder 是可以将文本转换为向量的供应商
+type Embedder interface {
+	AIProvider
+	// Embed 返回每段文本的向量，顺序与输入一致，输入较多时分批请求
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// EmbeddingInfo 返回使用的向量模型及其维度
+	EmbeddingInfo() EmbeddingInfo
 }